@@ -0,0 +1,405 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// how long a deleted account can still be restored by logging in
+	accountDeletionGracePeriod 	= 30 * 24 * time.Hour
+	accountPurgeInterval 		= 10 * time.Minute
+	accountPurgeBatchSize 		= 50
+	accountExportPageSize 		= 100
+)
+
+// ExportedToken describes a refresh token without the token itself.
+type ExportedToken struct {
+	SessionID 	uuid.UUID 	`json:"session_id"`
+	CreatedAt 	time.Time 	`json:"created_at"`
+	UpdatedAt 	time.Time 	`json:"updated_at"`
+	LastUsedAt 	time.Time 	`json:"last_used_at"`
+	ExpiresAt 	time.Time 	`json:"expires_at"`
+	RotatedAt 	*time.Time 	`json:"rotated_at"`
+	RevokedAt 	*time.Time 	`json:"revoked_at"`
+	UserAgent 	string 		`json:"user_agent"`
+	IPAddress 	string 		`json:"ip_address"`
+}
+
+// AccountExport is everything we hold about a user, as handed to them. The
+// chirps aren't held here; writeAccountExport streams them after these fields.
+type AccountExport struct {
+	ExportedAt 		time.Time 		`json:"exported_at"`
+	User 			User 			`json:"user"`
+	RefreshTokens 	[]ExportedToken `json:"refresh_tokens"`
+	APIKeys 		[]APIKey 		`json:"api_keys"`
+}
+
+
+// deleteMe schedules the caller's account for deletion after the grace
+// period. Every session is signed out and every API key revoked; logging in
+// again cancels it.
+func (cfg *apiConfig) deleteMe(writer http.ResponseWriter, request *http.Request) {
+	type deleteData struct {
+		Password string `json:"password"`
+	}
+
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := deleteData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	user, err := queries.GetUserForUpdate(request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
+		return
+	}
+
+	if err := auth.CheckPasswordHash(requestData.Password, user.HashedPassword); err != nil {
+		responseError(writer, http.StatusForbidden, "Incorrect password", err)
+		return
+	}
+
+	// asking twice keeps the original date
+	if !user.DeleteAfter.Valid {
+		user, err = queries.ScheduleUserDeletion(request.Context(), database.ScheduleUserDeletionParams{
+			DeleteAfter: time.Now().UTC().Add(accountDeletionGracePeriod),
+			ID: userID,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error scheduling account deletion", err)
+			return
+		}
+	}
+
+	if err := queries.RevokeUserRefreshTokens(request.Context(), userID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+		return
+	}
+
+	// unlike sessions, keys don't come back when the user logs in again
+	if err := queries.RevokeUserAPIKeys(request.Context(), userID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking API keys", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error scheduling account deletion", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusAccepted, cfg.userFromDB(user))
+}
+
+
+// purgeDeletedAccounts removes the accounts whose grace period is over,
+// along with the media they uploaded.
+func (cfg *apiConfig) purgeDeletedAccounts(ctx context.Context) error {
+	userIDs, err := cfg.dbQueries.ListUsersDueForDeletion(ctx, accountPurgeBatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := cfg.purgeAccount(ctx, userID); err != nil {
+			return err
+		}
+	}
+	if len(userIDs) > 0 {
+		log.Printf("Deleted %d accounts", len(userIDs))
+	}
+
+	return nil
+}
+
+
+func (cfg *apiConfig) purgeAccount(ctx context.Context, userID uuid.UUID) error {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	// the user may have logged back in since the batch was listed
+	user, err := queries.GetUserForUpdate(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !user.DeleteAfter.Valid || user.DeleteAfter.Time.After(time.Now().UTC()) {
+		return nil
+	}
+
+	chirps, err := queries.ListUserChirps(ctx, userID)
+	if err != nil {
+		return err
+	}
+	attachments, err := queries.ListUserAttachments(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// everything else the user owns goes with the row
+	if err := queries.DeleteUser(ctx, userID); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	cfg.deleteAttachmentBlobs(ctx, attachments)
+	if user.AvatarKey != "" {
+		cfg.deleteBlobs(ctx, user.AvatarKey)
+	}
+	for _, chirp := range chirps {
+		if chirp.Published {
+			cfg.publishChirpDeleted(chirp.ID, userID)
+		}
+	}
+
+	return nil
+}
+
+
+// exportMe hands the caller a copy of their data, as JSON by default or as a
+// zip archive that also holds their uploaded media with format=zip.
+func (cfg *apiConfig) exportMe(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	format := request.URL.Query().Get("format")
+	if format != "" && format != "json" && format != "zip" {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Unknown export format %q", format), nil)
+		return
+	}
+
+	export, mediaKeys, err := cfg.buildAccountExport(request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error exporting account", err)
+		return
+	}
+
+	if format == "zip" {
+		writer.Header().Set("Content-Type", "application/zip")
+		writer.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.zip"`)
+	} else {
+		writer.Header().Set("Content-Type", "application/json")
+		writer.Header().Set("Content-Disposition", `attachment; filename="chirpy-export.json"`)
+	}
+	writer.WriteHeader(http.StatusOK)
+
+	// the status is already sent, so failures from here on can only be logged
+	if format == "zip" {
+		err = cfg.writeExportArchive(request.Context(), writer, export, mediaKeys)
+	} else {
+		err = cfg.writeAccountExport(request.Context(), writer, export)
+	}
+	if err != nil {
+		log.Printf("Error writing export for user %s: %v", userID, err)
+	}
+}
+
+
+// buildAccountExport gathers the user's data, apart from their chirps, along
+// with the storage keys of the media they uploaded.
+func (cfg *apiConfig) buildAccountExport(ctx context.Context, userID uuid.UUID) (AccountExport, []string, error) {
+	user, err := cfg.dbQueries.GetUserWithID(ctx, userID)
+	if err != nil {
+		return AccountExport{}, nil, err
+	}
+
+	attachments, err := cfg.dbQueries.ListUserAttachments(ctx, userID)
+	if err != nil {
+		return AccountExport{}, nil, err
+	}
+
+	mediaKeys := []string{}
+	if user.AvatarKey != "" {
+		mediaKeys = append(mediaKeys, user.AvatarKey)
+	}
+	for _, attachment := range attachments {
+		mediaKeys = append(mediaKeys, attachment.StorageKey)
+	}
+
+	tokens, err := cfg.dbQueries.ListUserRefreshTokens(ctx, userID)
+	if err != nil {
+		return AccountExport{}, nil, err
+	}
+
+	exportedTokens := make([]ExportedToken, len(tokens))
+	for i, token := range tokens {
+		exportedTokens[i] = ExportedToken{
+			SessionID: token.FamilyID,
+			CreatedAt: token.CreatedAt,
+			UpdatedAt: token.UpdatedAt,
+			LastUsedAt: token.LastUsedAt,
+			ExpiresAt: token.ExpiresAt,
+			RotatedAt: nullTimePointer(token.RotatedAt),
+			RevokedAt: nullTimePointer(token.RevokedAt),
+			UserAgent: token.UserAgent,
+			IPAddress: token.IpAddress,
+		}
+	}
+
+	keys, err := cfg.dbQueries.ListAPIKeys(ctx, userID)
+	if err != nil {
+		return AccountExport{}, nil, err
+	}
+
+	apiKeys := make([]APIKey, len(keys))
+	for i, key := range keys {
+		apiKeys[i] = apiKeyFromDB(key)
+	}
+
+	return AccountExport{
+		ExportedAt: time.Now().UTC(),
+		User: cfg.userFromDB(user),
+		RefreshTokens: exportedTokens,
+		APIKeys: apiKeys,
+	}, mediaKeys, nil
+}
+
+
+// writeAccountExport writes the export as indented JSON with a "chirps" list
+// after the other fields. Chirps are read and written a page at a time, so a
+// long history never has to fit in memory.
+func (cfg *apiConfig) writeAccountExport(ctx context.Context, destination io.Writer, export AccountExport) error {
+	header, err := json.MarshalIndent(export, "", "  ")
+	if err != nil {
+		return err
+	}
+	// reopen the object by dropping its closing "\n}"
+	header = header[:len(header)-2]
+	if _, err := fmt.Fprintf(destination, "%s,\n  \"chirps\": [", header); err != nil {
+		return err
+	}
+
+	userID := export.User.ID
+	params := database.ListChirpsAscParams{
+		ViewerID: userID,
+		AuthorID: uuid.NullUUID{UUID: userID, Valid: true},
+		Limit: accountExportPageSize,
+	}
+	written := 0
+	for {
+		rows, err := cfg.dbQueries.ListChirpsAsc(ctx, params)
+		if err != nil {
+			return err
+		}
+
+		chirps := make([]Chirp, len(rows))
+		for i, row := range rows {
+			chirps[i] = chirpFromDB(row)
+		}
+		if err := cfg.decorateChirps(ctx, userID, chirps); err != nil {
+			return err
+		}
+
+		for _, chirp := range chirps {
+			data, err := json.MarshalIndent(chirp, "    ", "  ")
+			if err != nil {
+				return err
+			}
+			separator := ",\n    "
+			if written == 0 {
+				separator = "\n    "
+			}
+			if _, err := fmt.Fprintf(destination, "%s%s", separator, data); err != nil {
+				return err
+			}
+			written++
+		}
+
+		if len(rows) < accountExportPageSize {
+			break
+		}
+		last := rows[len(rows)-1]
+		params.CursorCreatedAt = sql.NullTime{Time: last.CreatedAt, Valid: true}
+		params.CursorID = uuid.NullUUID{UUID: last.ID, Valid: true}
+	}
+
+	closing := "\n  ]\n}\n"
+	if written == 0 {
+		closing = "]\n}\n"
+	}
+	_, err = io.WriteString(destination, closing)
+	return err
+}
+
+
+// writeExportArchive streams the export as account.json followed by the
+// uploaded media under media/.
+func (cfg *apiConfig) writeExportArchive(ctx context.Context, destination io.Writer, export AccountExport, mediaKeys []string) error {
+	archive := zip.NewWriter(destination)
+
+	entry, err := archive.Create("account.json")
+	if err != nil {
+		return err
+	}
+	if err := cfg.writeAccountExport(ctx, entry, export); err != nil {
+		return err
+	}
+
+	for _, key := range mediaKeys {
+		if err := cfg.copyBlobToArchive(ctx, archive, key); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+
+func (cfg *apiConfig) copyBlobToArchive(ctx context.Context, archive *zip.Writer, key string) error {
+	blob, err := cfg.storage.Open(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	entry, err := archive.Create("media/" + key)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, blob)
+	return err
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	// marks our keys, so they are easy to spot when they leak
+	apiKeyPrefix 			= "chirpy_"
+	// how much of a key is kept in the clear to tell keys apart
+	apiKeyDisplayLength 	= len(apiKeyPrefix) + 8
+	maxAPIKeyNameLength 	= 100
+)
+
+// APIKey describes a personal API key. The key itself is only ever shown in
+// the response that creates it.
+type APIKey struct {
+	ID 			uuid.UUID 	`json:"id"`
+	Name 		string 		`json:"name"`
+	Prefix 		string 		`json:"prefix"`
+	Scopes 		[]string 	`json:"scopes"`
+	CreatedAt 	time.Time 	`json:"created_at"`
+	LastUsedAt 	*time.Time 	`json:"last_used_at"`
+	Key 		string 		`json:"key,omitempty"`
+}
+
+
+func apiKeyFromDB(apiKey database.ApiKey) APIKey {
+	return APIKey{
+		ID: apiKey.ID,
+		Name: apiKey.Name,
+		Prefix: apiKey.Prefix,
+		Scopes: apiKey.Scopes,
+		CreatedAt: apiKey.CreatedAt,
+		LastUsedAt: nullTimePointer(apiKey.LastUsedAt),
+	}
+}
+
+
+// createAPIKey makes a named key limited to the given scopes, which must all
+// be held by the credentials making the request. It is sent as
+// "Authorization: ApiKey <key>" and works until it is revoked.
+func (cfg *apiConfig) createAPIKey(writer http.ResponseWriter, request *http.Request) {
+	type createData struct {
+		Name 	string 		`json:"name"`
+		Scopes 	[]string 	`json:"scopes"`
+	}
+
+	userID, grantedScopes, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := createData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	if requestData.Name == "" {
+		responseError(writer, http.StatusBadRequest, "Name cannot be empty", nil)
+		return
+	}
+	if utf8.RuneCountInString(requestData.Name) > maxAPIKeyNameLength {
+		responseError(writer, http.StatusBadRequest, "Name is too long", nil)
+		return
+	}
+
+	if err := auth.ValidateScopes(requestData.Scopes); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+	// a key can't do more than the credentials that created it
+	for _, scope := range requestData.Scopes {
+		if !auth.HasScope(grantedScopes, scope) {
+			responseError(writer, http.StatusForbidden, fmt.Sprintf("Cannot grant the %s scope without holding it", scope), nil)
+			return
+		}
+	}
+
+	secret, err := auth.MakeRefreshToken()
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error generating API key", err)
+		return
+	}
+	key := apiKeyPrefix + secret
+
+	apiKey, err := cfg.dbQueries.CreateAPIKey(request.Context(), database.CreateAPIKeyParams{
+		UserID: userID,
+		Name: requestData.Name,
+		KeyHash: auth.HashToken(key),
+		Prefix: key[:apiKeyDisplayLength],
+		Scopes: requestData.Scopes,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error creating API key", err)
+		return
+	}
+
+	response := apiKeyFromDB(apiKey)
+	response.Key = key
+	responseJSON(writer, http.StatusCreated, response)
+}
+
+
+func (cfg *apiConfig) getAPIKeys(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListAPIKeys(request.Context(), userID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching API keys", err)
+		return
+	}
+
+	apiKeys := make([]APIKey, len(rows))
+	for i, row := range rows {
+		apiKeys[i] = apiKeyFromDB(row)
+	}
+
+	responseJSON(writer, http.StatusOK, apiKeys)
+}
+
+
+func (cfg *apiConfig) deleteAPIKey(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	keyID, err := uuid.Parse(request.PathValue("keyID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	revoked, err := cfg.dbQueries.RevokeAPIKey(request.Context(), database.RevokeAPIKeyParams{
+		ID: keyID,
+		UserID: userID,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking API key", err)
+		return
+	}
+	if revoked == 0 {
+		responseError(writer, http.StatusNotFound, "API key does not exist", nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/media"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/storage"
+	"github.com/google/uuid"
+)
+
+const (
+	maxAttachmentSize      = 5 << 20
+	maxAttachmentsPerChirp = 4
+)
+
+type Attachment struct {
+	ID 				uuid.UUID 	`json:"id"`
+	URL 			string 		`json:"url"`
+	ThumbnailURL 	string 		`json:"thumbnail_url"`
+	ContentType 	string 		`json:"content_type"`
+	SizeBytes 		int64 		`json:"size_bytes"`
+	Width 			int32 		`json:"width"`
+	Height 			int32 		`json:"height"`
+}
+
+
+func (cfg *apiConfig) attachmentFromDB(attachment database.ChirpAttachment) Attachment {
+	return Attachment{
+		ID: attachment.ID,
+		URL: cfg.storage.URL(attachment.StorageKey),
+		ThumbnailURL: cfg.storage.URL(attachment.ThumbnailKey),
+		ContentType: attachment.ContentType,
+		SizeBytes: attachment.SizeBytes,
+		Width: attachment.Width,
+		Height: attachment.Height,
+	}
+}
+
+
+// uploadChirpAttachment attaches the image sent as the "file" field of a
+// multipart form to one of the caller's chirps.
+func (cfg *apiConfig) uploadChirpAttachment(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	// leave some room for the multipart framing around the file itself
+	request.Body = http.MaxBytesReader(writer, request.Body, maxAttachmentSize+(1<<20))
+	if err := request.ParseMultipartForm(maxAttachmentSize); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			responseError(writer, http.StatusRequestEntityTooLarge, "Attachment is too large", err)
+			return
+		}
+		responseError(writer, http.StatusBadRequest, "Malformed multipart form", err)
+		return
+	}
+	defer request.MultipartForm.RemoveAll()
+
+	file, header, err := request.FormFile("file")
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, "Missing file", err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAttachmentSize {
+		responseError(writer, http.StatusRequestEntityTooLarge, "Attachment is too large", nil)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error reading upload", err)
+		return
+	}
+
+	// the client's Content-Type is ignored, media sniffs the real one
+	img, err := media.DecodeImage(data)
+	if err != nil {
+		responseError(writer, http.StatusUnsupportedMediaType, err.Error(), err)
+		return
+	}
+
+	thumbnail, err := img.Thumbnail()
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error generating thumbnail", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	// locking the chirp also serializes concurrent uploads to it
+	chirp, err := queries.GetChirpForUpdate(request.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		responseError(writer, http.StatusForbidden, "Unauthorized request", nil)
+		return
+	}
+
+	count, err := queries.CountChirpAttachments(request.Context(), chirp.ID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error counting attachments", err)
+		return
+	}
+	if count >= maxAttachmentsPerChirp {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Chirps can have at most %d attachments", maxAttachmentsPerChirp), nil)
+		return
+	}
+
+	attachmentID := uuid.New()
+	storageKey := attachmentID.String() + img.Extension
+	thumbnailKey := attachmentID.String() + "-thumb.png"
+
+	if err := cfg.storage.Put(request.Context(), storageKey, bytes.NewReader(data)); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error storing attachment", err)
+		return
+	}
+	if err := cfg.storage.Put(request.Context(), thumbnailKey, bytes.NewReader(thumbnail)); err != nil {
+		cfg.deleteBlobs(request.Context(), storageKey)
+		responseError(writer, http.StatusInternalServerError, "Error storing thumbnail", err)
+		return
+	}
+
+	attachment, err := queries.CreateChirpAttachment(request.Context(), database.CreateChirpAttachmentParams{
+		ID: attachmentID,
+		ChirpID: chirp.ID,
+		ContentType: img.ContentType,
+		SizeBytes: int64(len(data)),
+		Width: int32(img.Width),
+		Height: int32(img.Height),
+		StorageKey: storageKey,
+		ThumbnailKey: thumbnailKey,
+	})
+	if err != nil {
+		cfg.deleteBlobs(request.Context(), storageKey, thumbnailKey)
+		responseError(writer, http.StatusInternalServerError, "Error saving attachment", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		cfg.deleteBlobs(request.Context(), storageKey, thumbnailKey)
+		responseError(writer, http.StatusInternalServerError, "Error saving attachment", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusCreated, cfg.attachmentFromDB(attachment))
+}
+
+
+// serveMedia streams a stored blob back to clients.
+func (cfg *apiConfig) serveMedia(writer http.ResponseWriter, request *http.Request) {
+	key := request.PathValue("key")
+
+	blob, err := cfg.storage.Open(request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			writer.WriteHeader(http.StatusNotFound)
+			return
+		}
+		responseError(writer, http.StatusBadRequest, "Invalid media key", err)
+		return
+	}
+	defer blob.Close()
+
+	if contentType := mime.TypeByExtension(filepath.Ext(key)); contentType != "" {
+		writer.Header().Set("Content-Type", contentType)
+	}
+	// keys are never reused, so blobs can be cached forever
+	writer.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(writer, blob); err != nil {
+		log.Printf("Error serving media %s: %v", key, err)
+	}
+}
+
+
+// deleteBlobs removes stored blobs on a best-effort basis; failures only
+// leave orphaned files behind, so they are logged rather than returned.
+func (cfg *apiConfig) deleteBlobs(ctx context.Context, keys ...string) {
+	for _, key := range keys {
+		if err := cfg.storage.Delete(ctx, key); err != nil {
+			log.Printf("Error deleting blob %s: %v", key, err)
+		}
+	}
+}
+
+
+// deleteAttachmentBlobs removes the files behind attachments whose rows
+// have already been deleted.
+func (cfg *apiConfig) deleteAttachmentBlobs(ctx context.Context, attachments []database.ChirpAttachment) {
+	for _, attachment := range attachments {
+		cfg.deleteBlobs(ctx, attachment.StorageKey, attachment.ThumbnailKey)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// SavedChirp is a chirp in a user's bookmarks or in one of their collections.
+type SavedChirp struct {
+	SavedAt 	time.Time 	`json:"saved_at"`
+	Chirp 		Chirp 		`json:"chirp"`
+}
+
+
+func savedChirpPosition(saved SavedChirp) (time.Time, uuid.UUID) {
+	return saved.SavedAt, saved.Chirp.ID
+}
+
+
+var bookmarkReaction = chirpReaction{
+	name: "bookmark",
+	add: func(ctx context.Context, queries *database.Queries, userID, chirpID uuid.UUID) error {
+		return queries.BookmarkChirp(ctx, database.BookmarkChirpParams{UserID: userID, ChirpID: chirpID})
+	},
+	remove: func(ctx context.Context, queries *database.Queries, userID, chirpID uuid.UUID) error {
+		return queries.RemoveBookmark(ctx, database.RemoveBookmarkParams{UserID: userID, ChirpID: chirpID})
+	},
+}
+
+
+func (cfg *apiConfig) bookmarkChirp(writer http.ResponseWriter, request *http.Request) {
+	cfg.setChirpReaction(writer, request, bookmarkReaction, true)
+}
+
+
+func (cfg *apiConfig) removeBookmark(writer http.ResponseWriter, request *http.Request) {
+	cfg.setChirpReaction(writer, request, bookmarkReaction, false)
+}
+
+
+// getBookmarks lists the authenticated user's bookmarks. Bookmarks are
+// private, so there is no way to list anyone else's.
+func (cfg *apiConfig) getBookmarks(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var saved []SavedChirp
+	if page.scanDescending() {
+		rows, err := cfg.dbQueries.ListBookmarksDesc(request.Context(), database.ListBookmarksDescParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error fetching bookmarks", err)
+			return
+		}
+		for _, row := range rows {
+			saved = append(saved, SavedChirp{SavedAt: row.SavedAt, Chirp: chirpFromDB(row.Chirp)})
+		}
+	} else {
+		rows, err := cfg.dbQueries.ListBookmarksAsc(request.Context(), database.ListBookmarksAscParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error fetching bookmarks", err)
+			return
+		}
+		for _, row := range rows {
+			saved = append(saved, SavedChirp{SavedAt: row.SavedAt, Chirp: chirpFromDB(row.Chirp)})
+		}
+	}
+
+	cfg.respondSavedChirps(writer, request, userID, page, saved)
+}
+
+
+// respondSavedChirps decorates a page of saved chirps for their owner and
+// writes it out.
+func (cfg *apiConfig) respondSavedChirps(writer http.ResponseWriter, request *http.Request, userID uuid.UUID, page pageRequest, saved []SavedChirp) {
+	chirps := make([]Chirp, len(saved))
+	for i := range saved {
+		chirps[i] = saved[i].Chirp
+	}
+
+	if err := cfg.decorateChirps(request.Context(), userID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	for i := range saved {
+		saved[i].Chirp = chirps[i]
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, saved, savedChirpPosition))
+}
@@ -1,41 +1,205 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 
 	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
 	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/moderation"
 	"github.com/google/uuid"
 )
 
+// the limit for users without Chirpy Red
+const maxChirpLength = 140
+
 type Chirp struct {
 	ID 			uuid.UUID 	`json:"id"`
 	CreatedAt 	time.Time 	`json:"created_at"`
 	UpdatedAt 	time.Time 	`json:"updated_at"`
 	Body 		string 		`json:"body"`
 	UserID 		uuid.UUID 	`json:"user_id"`
+	// nil for tombstones
+	Author 		*Author 	`json:"author"`
+	ParentChirpID 	*uuid.UUID 	`json:"parent_chirp_id"`
+	Deleted 	bool 		`json:"deleted,omitempty"`
+	// scheduled chirps are only visible to their author until publish_at
+	PublishAt 	*time.Time 	`json:"publish_at,omitempty"`
+	Scheduled 	bool 		`json:"scheduled,omitempty"`
+	LikeCount 	int32 		`json:"like_count"`
+	RechirpCount 	int32 		`json:"rechirp_count"`
+	// only set when the request carries a bearer token
+	LikedByMe 	*bool 		`json:"liked_by_me,omitempty"`
+	Entities 	ChirpEntities 	`json:"entities"`
+	Attachments 	[]Attachment 	`json:"attachments"`
+	Poll 		*Poll 		`json:"poll,omitempty"`
 }
 
+
+// chirpFromDB converts a database row into its API representation.
+// Deleted chirps are returned as tombstones so threads stay intact.
+func chirpFromDB(chirp database.Chirp) Chirp {
+	converted := Chirp{
+		ID: chirp.ID,
+		CreatedAt: chirp.CreatedAt,
+		UpdatedAt: chirp.UpdatedAt,
+		Body: chirp.Body,
+		UserID: chirp.UserID,
+		LikeCount: chirp.LikeCount,
+		RechirpCount: chirp.RechirpCount,
+	}
+
+	if chirp.ParentChirpID.Valid {
+		parentID := chirp.ParentChirpID.UUID
+		converted.ParentChirpID = &parentID
+	}
+
+	if chirp.PublishAt.Valid {
+		publishAt := chirp.PublishAt.Time
+		converted.PublishAt = &publishAt
+		converted.Scheduled = !chirp.Published
+	}
+
+	if chirp.DeletedAt.Valid {
+		converted.Deleted = true
+		converted.Body = ""
+		converted.UserID = uuid.Nil
+	}
+
+	converted.Entities = parseEntities(converted.Body)
+	converted.Attachments = []Attachment{}
+
+	return converted
+}
+
+
+// decorateChirps fills in the viewer-dependent parts of the given chirps.
+// viewerID is uuid.Nil for anonymous requests.
+func (cfg *apiConfig) decorateChirps(ctx context.Context, viewerID uuid.UUID, chirps []Chirp) error {
+	if len(chirps) == 0 {
+		return nil
+	}
+
+	chirpIDs := make([]uuid.UUID, len(chirps))
+	for i, chirp := range chirps {
+		chirpIDs[i] = chirp.ID
+	}
+
+	if err := cfg.decorateAuthors(ctx, chirps); err != nil {
+		return err
+	}
+
+	mentions, err := cfg.dbQueries.ListChirpMentions(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	// chirp ID -> mention text -> mentioned user
+	mentionedUsers := make(map[uuid.UUID]map[string]uuid.UUID)
+	for _, mention := range mentions {
+		if mentionedUsers[mention.ChirpID] == nil {
+			mentionedUsers[mention.ChirpID] = make(map[string]uuid.UUID)
+		}
+		mentionedUsers[mention.ChirpID][mention.MentionText] = mention.UserID
+	}
+
+	for i := range chirps {
+		chirps[i].Entities.resolveMentions(mentionedUsers[chirps[i].ID])
+	}
+
+	attachments, err := cfg.dbQueries.ListChirpAttachments(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+
+	chirpAttachments := make(map[uuid.UUID][]Attachment)
+	for _, attachment := range attachments {
+		chirpAttachments[attachment.ChirpID] = append(chirpAttachments[attachment.ChirpID], cfg.attachmentFromDB(attachment))
+	}
+
+	for i := range chirps {
+		if found, ok := chirpAttachments[chirps[i].ID]; ok {
+			chirps[i].Attachments = found
+		}
+	}
+
+	if err := cfg.decoratePolls(ctx, viewerID, chirps, chirpIDs); err != nil {
+		return err
+	}
+
+	if viewerID == uuid.Nil {
+		return nil
+	}
+
+	likedIDs, err := cfg.dbQueries.ListLikedChirpIDs(ctx, database.ListLikedChirpIDsParams{
+		UserID: viewerID,
+		ChirpIds: chirpIDs,
+	})
+	if err != nil {
+		return err
+	}
+
+	liked := make(map[uuid.UUID]bool, len(likedIDs))
+	for _, id := range likedIDs {
+		liked[id] = true
+	}
+
+	for i := range chirps {
+		likedByMe := liked[chirps[i].ID]
+		chirps[i].LikedByMe = &likedByMe
+	}
+
+	return nil
+}
+
+
+// prepareChirpBody enforces the user's length limit and runs the body through
+// the moderation filter. On failure it writes the error response itself.
+func (cfg *apiConfig) prepareChirpBody(writer http.ResponseWriter, body string, maxLength int) (string, bool) {
+	if len(body) > maxLength {
+		responseError(writer, http.StatusBadRequest, "Chirp is too long", nil)
+		return "", false
+	}
+
+	cleaned, err := cfg.cleanUpChirp(body)
+	if err != nil {
+		if errors.Is(err, moderation.ErrRejected) {
+			responseError(writer, http.StatusBadRequest, "Chirp contains a banned word", err)
+			return "", false
+		}
+		responseError(writer, http.StatusInternalServerError, "Error cleaning up chirp", err)
+		return "", false
+	}
+
+	return cleaned, true
+}
+
+
 func (cfg *apiConfig) createChirp(writer http.ResponseWriter, request *http.Request) {
 	type chirpData struct {
 		Body string `json:"body"`
 		UserID uuid.UUID `json:"user_id"`
+		ParentChirpID *uuid.UUID `json:"parent_chirp_id"`
+		PublishAt *time.Time `json:"publish_at"`
+		Poll *pollData `json:"poll"`
 	}
 
-	token, err := auth.GetBearerToken(request.Header)
-	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Missing/Invalid authorization token in header", err)
+	posterID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
 		return
 	}
 
-	posterID, err := auth.ValidateJWT(token, cfg.tokenSecret)
-	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Invalid JWT token", err)
+	entitlements, ok := cfg.requireEntitlements(writer, request, posterID)
+	if !ok {
+		return
+	}
+
+	if !cfg.allowChirpWrite(writer, posterID, entitlements) {
 		return
 	}
 
@@ -46,58 +210,144 @@ func (cfg *apiConfig) createChirp(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	if len(requestData.Body) > 140 {
-		responseError(writer, http.StatusInternalServerError, "Chirp is too long", nil)
+	requestData.Body, ok = cfg.prepareChirpBody(writer, requestData.Body, entitlements.MaxChirpLength)
+	if !ok {
 		return
 	}
-
-	requestData.Body = cleanUpChirp(requestData.Body)
 	// JWT determines the user posting the chirp
 	requestData.UserID = posterID
 
-	newChirp, err := cfg.dbQueries.CreateChirp(request.Context(), database.CreateChirpParams{
+	var publishAt sql.NullTime
+	if requestData.PublishAt != nil {
+		if !entitlements.CanScheduleChirps {
+			responseError(writer, http.StatusForbidden, "Scheduling chirps requires Chirpy Red", nil)
+			return
+		}
+		if !requestData.PublishAt.After(time.Now()) {
+			responseError(writer, http.StatusBadRequest, "publish_at must be in the future", nil)
+			return
+		}
+		publishAt = sql.NullTime{Time: requestData.PublishAt.UTC(), Valid: true}
+	}
+
+	var poll pollData
+	if requestData.Poll != nil {
+		opensAt := time.Now()
+		if publishAt.Valid {
+			opensAt = publishAt.Time
+		}
+		poll, ok = cfg.preparePoll(writer, *requestData.Poll, opensAt)
+		if !ok {
+			return
+		}
+	}
+
+	var parentChirpID uuid.NullUUID
+	if requestData.ParentChirpID != nil {
+		parent, err := cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+			ID: *requestData.ParentChirpID,
+			ViewerID: posterID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				responseError(writer, http.StatusNotFound, "Parent chirp does not exist", err)
+				return
+			}
+			responseError(writer, http.StatusInternalServerError, "Error fetching parent chirp", err)
+			return
+		}
+		if !parent.Published {
+			responseError(writer, http.StatusBadRequest, "Cannot reply to a chirp that has not been published yet", nil)
+			return
+		}
+		parentChirpID = uuid.NullUUID{UUID: parent.ID, Valid: true}
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	newChirp, err := queries.CreateChirp(request.Context(), database.CreateChirpParams{
 		Body: requestData.Body,
-		UserID: requestData.UserID,})
+		UserID: requestData.UserID,
+		ParentChirpID: parentChirpID,
+		PublishAt: publishAt,})
 
 	if err != nil {
 		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error creating chirp: %s", err), err)
 		return
 	}
 
-	nChirp := Chirp(newChirp)
-	responseJSON(writer, http.StatusCreated, nChirp)
+	if err := storeChirpEntities(request.Context(), queries, newChirp); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error saving hashtags and mentions", err)
+		return
+	}
+
+	if requestData.Poll != nil {
+		if err := storePoll(request.Context(), queries, newChirp.ID, poll); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error saving poll", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error saving chirp", err)
+		return
+	}
+
+	nChirp := chirpFromDB(newChirp)
+	chirps := []Chirp{nChirp}
+	if err := cfg.decorateChirps(request.Context(), posterID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	// scheduled chirps are announced by the publisher once they go live
+	if newChirp.Published {
+		cfg.publishChirp(chirpCreatedEvent, chirps[0])
+	}
+	responseJSON(writer, http.StatusCreated, chirps[0])
 
 }
 
 
 func (cfg *apiConfig) getChirp(writer http.ResponseWriter, request *http.Request) {
+	viewerID, ok := cfg.optionalViewer(writer, request)
+	if !ok {
+		return
+	}
+
 	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
 	if err != nil {
 		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %s", err), err)
 		return
 	}
 
-	chirpData, err := cfg.dbQueries.GetChirp(request.Context(), chirpID)
+	chirpData, err := cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+		ID: chirpID,
+		ViewerID: viewerID,
+	})
 	if err != nil {
 		writer.WriteHeader(http.StatusNotFound)
 		return
 	}
 
-	chirp := Chirp(chirpData)
-	responseJSON(writer, http.StatusOK, chirp)
+	chirps := []Chirp{chirpFromDB(chirpData)}
+	if err := cfg.decorateChirps(request.Context(), viewerID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+	responseJSON(writer, http.StatusOK, chirps[0])
 }
 
 
 func (cfg *apiConfig) deleteChirp(writer http.ResponseWriter, request *http.Request) {
-	accessToken, err := auth.GetBearerToken(request.Header)
-	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Missing/Malformed auth token in header", err)
-		return
-	}
-
-	userID, err := auth.ValidateJWT(accessToken, cfg.tokenSecret)
-	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Invalid auth token", err)
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
 		return
 	}
 
@@ -107,7 +357,10 @@ func (cfg *apiConfig) deleteChirp(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	chirpData, err := cfg.dbQueries.GetChirp(request.Context(), chirpID)
+	chirpData, err := cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+		ID: chirpID,
+		ViewerID: userID,
+	})
 	if err != nil {
 		writer.WriteHeader(http.StatusNotFound)
 		return
@@ -118,79 +371,135 @@ func (cfg *apiConfig) deleteChirp(writer http.ResponseWriter, request *http.Requ
 		return
 	}
 
-	err = cfg.dbQueries.DeleteChirp(request.Context(), chirpData.ID)
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
 	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	removedAttachments, err := tombstoneChirp(request.Context(), queries, chirpData.ID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error deleting chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
 		responseError(writer, http.StatusInternalServerError, "Error deleting chirp", err)
 		return
 	}
 
+	cfg.deleteAttachmentBlobs(request.Context(), removedAttachments)
+	if chirpData.Published {
+		cfg.publishChirpDeleted(chirpData.ID, chirpData.UserID)
+	}
+
 	writer.WriteHeader(http.StatusNoContent)
 }
 
 
-func (cfg *apiConfig) getAllChirps(writer http.ResponseWriter, request *http.Request) {
+// tombstoneChirp deletes a chirp's content while leaving the row behind so
+// replies keep their parent. Bookmarks and collection entries go too, since
+// the row surviving means the foreign keys never cascade. The removed
+// attachments are returned so their blobs can be deleted once the
+// transaction commits.
+func tombstoneChirp(ctx context.Context, queries *database.Queries, chirpID uuid.UUID) ([]database.ChirpAttachment, error) {
+	if err := queries.DeleteChirp(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := queries.DeleteChirpRevisions(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := queries.DeleteChirpTags(ctx, chirpID); err != nil {
+		return nil, err
+	}
 
-	var allChirps []database.Chirp
-	var err error
-	var sortOrder bool
+	if err := queries.DeleteChirpMentions(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := queries.DeleteChirpBookmarks(ctx, chirpID); err != nil {
+		return nil, err
+	}
+
+	if err := queries.DeleteChirpFromCollections(ctx, chirpID); err != nil {
+		return nil, err
+	}
 
-	sortQuery := request.URL.Query().Get("sort")
-	if sortQuery == "" || sortQuery == "asc" {
-		sortOrder = false
-	} else if sortQuery == "desc" {
-		sortOrder = true
+	if err := queries.DeletePoll(ctx, chirpID); err != nil {
+		return nil, err
 	}
 
+	return queries.DeleteChirpAttachments(ctx, chirpID)
+}
+
+
+func (cfg *apiConfig) getAllChirps(writer http.ResponseWriter, request *http.Request) {
+	viewerID, ok := cfg.optionalViewer(writer, request)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var authorID uuid.NullUUID
 	authorIDString := request.URL.Query().Get("author_id")
 	if authorIDString != "" {
-		authorID, err := uuid.Parse(authorIDString)
+		authorID.UUID, err = uuid.Parse(authorIDString)
 		if err != nil {
 			responseError(writer, http.StatusBadRequest, "Malformed User ID", err)
 			return
 		}
+		authorID.Valid = true
+	}
 
-		allChirps, err = cfg.dbQueries.GetChirpsByUser(request.Context(), authorID)
-		if err != nil {
-			if err == sql.ErrNoRows {
-				responseError(writer, http.StatusNotFound, "Given author has no chirps", err)
-				return
-			}
-			responseError(writer, http.StatusInternalServerError, "Error fetching chirps", err)
-			return
-		}
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var dbChirps []database.Chirp
+	// fetch one extra row so we know whether another page follows
+	if page.scanDescending() {
+		dbChirps, err = cfg.dbQueries.ListChirpsDesc(request.Context(), database.ListChirpsDescParams{
+			ViewerID: viewerID,
+			AuthorID: authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
 	} else {
-		allChirps, err = cfg.dbQueries.AllChirps(request.Context())
-		if err != nil {
-			responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error fetching chirps: %s", err), err)
-		}
+		dbChirps, err = cfg.dbQueries.ListChirpsAsc(request.Context(), database.ListChirpsAscParams{
+			ViewerID: viewerID,
+			AuthorID: authorID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error fetching chirps: %s", err), err)
+		return
 	}
 
-	numChirps := len(allChirps)
-	chirps := make([]Chirp, numChirps)
+	chirps := make([]Chirp, len(dbChirps))
+	for i, chirp := range dbChirps {
+		chirps[i] = chirpFromDB(chirp)
+	}
 
-	for i, chirp := range allChirps {
-		if sortOrder {
-			chirps[numChirps - i - 1] = Chirp(chirp)
-		} else {
-			chirps[i] = Chirp(chirp)
-		}
+	if err := cfg.decorateChirps(request.Context(), viewerID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
 	}
 
-	responseJSON(writer, http.StatusOK, chirps)
+	responseJSON(writer, http.StatusOK, buildPage(page, chirps, chirpPosition))
 }
 
 
-func cleanUpChirp(chirp string) string {
-	words := strings.Split(chirp, " ")
-	censorString := "****"
-	badWords := map[string]bool{"kerfuffle": true, "sharbert": true, "fornax": true}
-
-	for index, word := range words {
-		if badWords[strings.ToLower(word)] {
-			// word is blacklisted
-			words[index] = censorString
-		}
-	}
-
-	return strings.Join(words, " ")
+func chirpPosition(chirp Chirp) (time.Time, uuid.UUID) {
+	return chirp.CreatedAt, chirp.ID
 }
@@ -3,6 +3,7 @@ package main
 import (
     "testing"
 
+    "github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/moderation"
 )
 
 
@@ -88,7 +89,7 @@ func TestCleanUpChirp(t *testing.T) {
         {
 			name:     "profane word with punctuation",
 			input:	  "hello world kerfuffle!",
-			expected: "hello world kerfuffle!",
+			expected: "hello world ****!",
 		},
 		{
 			name:     "profane word with capitalization",
@@ -118,18 +119,32 @@ func TestCleanUpChirp(t *testing.T) {
 		{
 			name:     "mixed spaces and punctuation",
 			input:    "sharbert!   kerfuffle?    fornax...",
-			expected: "sharbert!   kerfuffle?    fornax...",
+			expected: "****!   ****?    ****...",
+		},
+		{
+			name:     "accented and full-width letters",
+			input:    "kérfüffle ｆｏｒｎａｘ",
+			expected: "**** ****",
 		},
 		{
 			name:     "multiple punctuation marks",
 			input:    "sharbert!!! kerfuffle??? fornax...",
-			expected: "sharbert!!! kerfuffle??? fornax...",
+			expected: "****!!! ****??? ****...",
 		},
 	}
 
+    filter, err := moderation.NewFilter(moderation.DefaultRules)
+    if err != nil {
+        t.Fatalf("unexpected error: %v", err)
+    }
+    cfg := apiConfig{moderation: filter}
+
     for _, tt := range tests {
         t.Run(tt.name, func(t *testing.T) {
-            result := cleanUpChirp(tt.input)
+            result, err := cfg.cleanUpChirp(tt.input)
+            if err != nil {
+                t.Fatalf("unexpected error: %v", err)
+            }
             if result != tt.expected {
                 t.Errorf("got %q, want %q", result, tt.expected)
             }
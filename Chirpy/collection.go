@@ -0,0 +1,311 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const maxCollectionNameLength = 64
+
+// Collection is a named, private list of saved chirps. Only its owner can
+// see it; everyone else gets a 404.
+type Collection struct {
+	ID 			uuid.UUID 	`json:"id"`
+	Name 		string 		`json:"name"`
+	CreatedAt 	time.Time 	`json:"created_at"`
+	UpdatedAt 	time.Time 	`json:"updated_at"`
+}
+
+
+func collectionFromDB(collection database.Collection) Collection {
+	return Collection{
+		ID: collection.ID,
+		Name: collection.Name,
+		CreatedAt: collection.CreatedAt,
+		UpdatedAt: collection.UpdatedAt,
+	}
+}
+
+
+func collectionPosition(collection Collection) (time.Time, uuid.UUID) {
+	return collection.CreatedAt, collection.ID
+}
+
+
+func (cfg *apiConfig) createCollection(writer http.ResponseWriter, request *http.Request) {
+	type collectionData struct {
+		Name string `json:"name"`
+	}
+
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := collectionData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	name := strings.TrimSpace(requestData.Name)
+	if name == "" {
+		responseError(writer, http.StatusBadRequest, "Collection name is required", nil)
+		return
+	}
+	if utf8.RuneCountInString(name) > maxCollectionNameLength {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Collection names can be at most %d characters", maxCollectionNameLength), nil)
+		return
+	}
+
+	collection, err := cfg.dbQueries.CreateCollection(request.Context(), database.CreateCollectionParams{
+		UserID: userID,
+		Name: name,
+	})
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			responseError(writer, http.StatusConflict, "A collection with that name already exists", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error creating collection", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusCreated, collectionFromDB(collection))
+}
+
+
+func (cfg *apiConfig) getCollections(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var rows []database.Collection
+	if page.scanDescending() {
+		rows, err = cfg.dbQueries.ListCollectionsDesc(request.Context(), database.ListCollectionsDescParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		rows, err = cfg.dbQueries.ListCollectionsAsc(request.Context(), database.ListCollectionsAscParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching collections", err)
+		return
+	}
+
+	collections := make([]Collection, len(rows))
+	for i, row := range rows {
+		collections[i] = collectionFromDB(row)
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, collections, collectionPosition))
+}
+
+
+func (cfg *apiConfig) deleteCollection(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	collectionID, err := uuid.Parse(request.PathValue("collectionID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteCollection(request.Context(), database.DeleteCollectionParams{
+		ID: collectionID,
+		UserID: userID,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error deleting collection", err)
+		return
+	}
+	if deleted == 0 {
+		responseError(writer, http.StatusNotFound, "Collection does not exist", nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+func (cfg *apiConfig) getCollectionChirps(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	collection, ok := cfg.ownedCollection(writer, request, userID)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var saved []SavedChirp
+	if page.scanDescending() {
+		rows, err := cfg.dbQueries.ListCollectionChirpsDesc(request.Context(), database.ListCollectionChirpsDescParams{
+			CollectionID: collection.ID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error fetching collection", err)
+			return
+		}
+		for _, row := range rows {
+			saved = append(saved, SavedChirp{SavedAt: row.SavedAt, Chirp: chirpFromDB(row.Chirp)})
+		}
+	} else {
+		rows, err := cfg.dbQueries.ListCollectionChirpsAsc(request.Context(), database.ListCollectionChirpsAscParams{
+			CollectionID: collection.ID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error fetching collection", err)
+			return
+		}
+		for _, row := range rows {
+			saved = append(saved, SavedChirp{SavedAt: row.SavedAt, Chirp: chirpFromDB(row.Chirp)})
+		}
+	}
+
+	cfg.respondSavedChirps(writer, request, userID, page, saved)
+}
+
+
+func (cfg *apiConfig) addChirpToCollection(writer http.ResponseWriter, request *http.Request) {
+	cfg.setCollectionChirp(writer, request, true)
+}
+
+
+func (cfg *apiConfig) removeChirpFromCollection(writer http.ResponseWriter, request *http.Request) {
+	cfg.setCollectionChirp(writer, request, false)
+}
+
+
+// setCollectionChirp adds a chirp to, or removes it from, one of the
+// authenticated user's collections. Both directions are idempotent.
+func (cfg *apiConfig) setCollectionChirp(writer http.ResponseWriter, request *http.Request, add bool) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	collection, ok := cfg.ownedCollection(writer, request, userID)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	if !add {
+		err = cfg.dbQueries.RemoveChirpFromCollection(request.Context(), database.RemoveChirpFromCollectionParams{
+			CollectionID: collection.ID,
+			ChirpID: chirpID,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error updating collection", err)
+			return
+		}
+		writer.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+		ID: chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+	if !chirp.Published {
+		responseError(writer, http.StatusBadRequest, "Chirp has not been published yet", nil)
+		return
+	}
+
+	err = cfg.dbQueries.AddChirpToCollection(request.Context(), database.AddChirpToCollectionParams{
+		CollectionID: collection.ID,
+		ChirpID: chirp.ID,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error updating collection", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+// ownedCollection loads the collection named in the path if it belongs to
+// userID. On failure it writes the error response itself.
+func (cfg *apiConfig) ownedCollection(writer http.ResponseWriter, request *http.Request, userID uuid.UUID) (database.Collection, bool) {
+	collectionID, err := uuid.Parse(request.PathValue("collectionID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return database.Collection{}, false
+	}
+
+	collection, err := cfg.dbQueries.GetCollection(request.Context(), database.GetCollectionParams{
+		ID: collectionID,
+		UserID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Collection does not exist", err)
+			return database.Collection{}, false
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching collection", err)
+		return database.Collection{}, false
+	}
+
+	return collection, true
+}
@@ -0,0 +1,357 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/mailer"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/ratelimit"
+)
+
+// what a mailed token can be used for
+const (
+	tokenPurposeVerifyEmail 	= "verify_email"
+	tokenPurposeResetPassword 	= "reset_password"
+)
+
+const (
+	verificationTokenLifetime 	= 24 * time.Hour
+	passwordResetTokenLifetime 	= time.Hour
+)
+
+// how often someone can ask for mail to be sent to one address
+var mailRate = ratelimit.Rate{Requests: 3, Per: time.Hour}
+
+
+// validateEmail accepts a bare address like walt@example.com, without a
+// display name or angle brackets.
+func validateEmail(email string) error {
+	address, err := mail.ParseAddress(email)
+	if err != nil || address.Address != email {
+		return fmt.Errorf("Invalid email address")
+	}
+	return nil
+}
+
+
+// issueUserToken invalidates the user's earlier tokens for the same purpose
+// and stores the hash of a new one. The token itself is returned for mailing.
+func issueUserToken(ctx context.Context, queries *database.Queries, user database.User, purpose string, lifetime time.Duration) (string, error) {
+	err := queries.InvalidateUserTokens(ctx, database.InvalidateUserTokensParams{
+		UserID: user.ID,
+		Purpose: purpose,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+
+	_, err = queries.CreateUserToken(ctx, database.CreateUserTokenParams{
+		UserID: user.ID,
+		Purpose: purpose,
+		TokenHash: auth.HashToken(token),
+		Email: user.Email,
+		ExpiresAt: time.Now().UTC().Add(lifetime),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := issueUserToken(ctx, cfg.dbQueries, user, tokenPurposeVerifyEmail, verificationTokenLifetime)
+	if err != nil {
+		return err
+	}
+
+	return cfg.mailer.Send(ctx, mailer.Message{
+		To: user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Hi @%s,\n\nConfirm this address with the following code, which is valid for %v:\n\n%s\n",
+			user.Handle, verificationTokenLifetime, token),
+	})
+}
+
+
+// allowMail applies the per-address mail rate limit. When it is hit it
+// answers 429 with a Retry-After header.
+func (cfg *apiConfig) allowMail(writer http.ResponseWriter, email string) bool {
+	allowed, wait := cfg.limiter.Allow("mail:"+strings.ToLower(email), mailRate)
+	if allowed {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	writer.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	responseError(writer, http.StatusTooManyRequests, fmt.Sprintf("Too many emails, try again in %d seconds", retryAfter), nil)
+	return false
+}
+
+
+// requestEmailVerification mails the authenticated user a new verification
+// code, replacing any earlier one.
+func (cfg *apiConfig) requestEmailVerification(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserWithID(request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
+		return
+	}
+
+	if user.EmailVerifiedAt.Valid {
+		responseError(writer, http.StatusConflict, "Email is already verified", nil)
+		return
+	}
+
+	if !cfg.allowMail(writer, user.Email) {
+		return
+	}
+
+	if err := cfg.sendVerificationEmail(request.Context(), user); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error sending verification email", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+
+// confirmEmailVerification marks the address a code was sent to as verified.
+// Codes for an address the user has since changed away from are rejected.
+func (cfg *apiConfig) confirmEmailVerification(writer http.ResponseWriter, request *http.Request) {
+	type confirmData struct {
+		Token string `json:"token"`
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := confirmData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	token, err := queries.ConsumeUserToken(request.Context(), database.ConsumeUserTokenParams{
+		TokenHash: auth.HashToken(requestData.Token),
+		Purpose: tokenPurposeVerifyEmail,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusBadRequest, "Invalid or expired token", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error checking token", err)
+		return
+	}
+
+	user, err := queries.MarkEmailVerified(request.Context(), database.MarkEmailVerifiedParams{
+		ID: token.UserID,
+		Email: token.Email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusBadRequest, "Invalid or expired token", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error verifying email", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error verifying email", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, cfg.userFromDB(user))
+}
+
+
+// requestPasswordReset mails a reset code if the address belongs to a user.
+// The response is the same either way so it can't be used to find accounts.
+func (cfg *apiConfig) requestPasswordReset(writer http.ResponseWriter, request *http.Request) {
+	type resetRequestData struct {
+		Email string `json:"email"`
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := resetRequestData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	if err := validateEmail(requestData.Email); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if !cfg.allowMail(writer, requestData.Email) {
+		return
+	}
+
+	user, err := cfg.dbQueries.GetUserWithEmail(request.Context(), requestData.Email)
+	if err == sql.ErrNoRows {
+		writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
+		return
+	}
+
+	// failures are only logged, since an error here would reveal that the
+	// address has an account
+	token, err := issueUserToken(request.Context(), cfg.dbQueries, user, tokenPurposeResetPassword, passwordResetTokenLifetime)
+	if err != nil {
+		log.Printf("Error creating reset token for user %s: %v", user.ID, err)
+		writer.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	err = cfg.mailer.Send(request.Context(), mailer.Message{
+		To: user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Hi @%s,\n\nSomeone asked to reset your password. If it was you, use this code within %v:\n\n%s\n\nOtherwise you can ignore this email.\n",
+			user.Handle, passwordResetTokenLifetime, token),
+	})
+	if err != nil {
+		log.Printf("Error sending reset email to user %s: %v", user.ID, err)
+	}
+
+	writer.WriteHeader(http.StatusAccepted)
+}
+
+
+// confirmPasswordReset sets a new password using a mailed code. Like any
+// password change it signs the user out everywhere. Receiving the code also
+// proves the user owns the address, so it counts as verifying it.
+func (cfg *apiConfig) confirmPasswordReset(writer http.ResponseWriter, request *http.Request) {
+	type resetData struct {
+		Token 		string `json:"token"`
+		Password 	string `json:"password"`
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := resetData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	if requestData.Password == "" {
+		responseError(writer, http.StatusBadRequest, "Password cannot be empty", nil)
+		return
+	}
+
+	hashedPassword, err := auth.HashPassword(requestData.Password)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error hashing password", err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	token, err := queries.ConsumeUserToken(request.Context(), database.ConsumeUserTokenParams{
+		TokenHash: auth.HashToken(requestData.Token),
+		Purpose: tokenPurposeResetPassword,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusBadRequest, "Invalid or expired token", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error checking token", err)
+		return
+	}
+
+	// codes mailed to an address the user has since moved away from are void
+	user, err := queries.SetUserPassword(request.Context(), database.SetUserPasswordParams{
+		HashedPassword: hashedPassword,
+		ID: token.UserID,
+		Email: token.Email,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusBadRequest, "Invalid or expired token", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error updating password", err)
+		return
+	}
+
+	if err := queries.RevokeUserRefreshTokens(request.Context(), user.ID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+		return
+	}
+	if err := queries.RevokeUserAPIKeys(request.Context(), user.ID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking API keys", err)
+		return
+	}
+
+	if !user.EmailVerifiedAt.Valid {
+		user, err = queries.MarkEmailVerified(request.Context(), database.MarkEmailVerifiedParams{
+			ID: user.ID,
+			Email: token.Email,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error verifying email", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error updating password", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, cfg.userFromDB(user))
+}
+
+
+// trySendVerificationEmail mails a code for a new or changed address. The
+// signup or change still goes through if the mail can't be sent; the user
+// can ask for another code.
+func (cfg *apiConfig) trySendVerificationEmail(ctx context.Context, user database.User) {
+	if err := cfg.sendVerificationEmail(ctx, user); err != nil {
+		log.Printf("Error sending verification email to user %s: %v", user.ID, err)
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// hashtags may start anywhere a word doesn't, e.g. "(#go)" but not "c#"
+var hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#])#([\p{L}\p{N}_]+)`)
+
+// mentions are either an email address or a bare handle
+var mentionPattern = regexp.MustCompile(
+	`(?:^|[^\p{L}\p{N}_@.])@([A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}|[A-Za-z0-9_]+)`)
+
+const maxTagLength = 64
+
+type Hashtag struct {
+	Tag 	string 	`json:"tag"`
+	Start 	int 	`json:"start"`
+	End 	int 	`json:"end"`
+}
+
+type Mention struct {
+	UserID 	uuid.UUID 	`json:"user_id"`
+	Text 	string 		`json:"text"`
+	Start 	int 		`json:"start"`
+	End 	int 		`json:"end"`
+}
+
+// ChirpEntities are the structured parts of a chirp body. Start and End are
+// character (code point) offsets into the body and cover the leading # or @.
+type ChirpEntities struct {
+	Hashtags 	[]Hashtag 	`json:"hashtags"`
+	Mentions 	[]Mention 	`json:"mentions"`
+}
+
+
+// parseEntities extracts hashtags and mention candidates from a chirp body.
+// Mentions come back without a user ID; they only become real mentions once
+// the text has been matched to a user.
+func parseEntities(body string) ChirpEntities {
+	entities := ChirpEntities{
+		Hashtags: []Hashtag{},
+		Mentions: []Mention{},
+	}
+
+	for _, match := range hashtagPattern.FindAllStringSubmatchIndex(body, -1) {
+		// match[2:4] is the tag itself, the # sits right before it
+		tag := normalizeTag(body[match[2]:match[3]])
+		if len(tag) > maxTagLength {
+			continue
+		}
+		entities.Hashtags = append(entities.Hashtags, Hashtag{
+			Tag: tag,
+			Start: runeOffset(body, match[2]-1),
+			End: runeOffset(body, match[3]),
+		})
+	}
+
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(body, -1) {
+		entities.Mentions = append(entities.Mentions, Mention{
+			Text: body[match[2]:match[3]],
+			Start: runeOffset(body, match[2]-1),
+			End: runeOffset(body, match[3]),
+		})
+	}
+
+	return entities
+}
+
+
+// uniqueTags lists each hashtag in the entities once, in order of appearance.
+func (entities ChirpEntities) uniqueTags() []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, hashtag := range entities.Hashtags {
+		if !seen[hashtag.Tag] {
+			seen[hashtag.Tag] = true
+			tags = append(tags, hashtag.Tag)
+		}
+	}
+	return tags
+}
+
+
+// mentionTexts lists each mentioned email or handle once, keyed the way
+// mentionKey stores them.
+func (entities ChirpEntities) mentionTexts() []string {
+	seen := map[string]bool{}
+	texts := []string{}
+	for _, mention := range entities.Mentions {
+		key := mentionKey(mention.Text)
+		if !seen[key] {
+			seen[key] = true
+			texts = append(texts, key)
+		}
+	}
+	return texts
+}
+
+
+// mentionKey is the form a mention is stored and matched in. Handles are
+// case-insensitive, emails are compared as written.
+func mentionKey(text string) string {
+	if strings.Contains(text, "@") {
+		return text
+	}
+	return strings.ToLower(text)
+}
+
+
+// resolveMentions keeps only the mentions whose text maps to a user,
+// filling in that user's ID.
+func (entities *ChirpEntities) resolveMentions(userIDs map[string]uuid.UUID) {
+	resolved := []Mention{}
+	for _, mention := range entities.Mentions {
+		if userID, ok := userIDs[mentionKey(mention.Text)]; ok {
+			mention.UserID = userID
+			resolved = append(resolved, mention)
+		}
+	}
+	entities.Mentions = resolved
+}
+
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
+
+
+func runeOffset(body string, byteOffset int) int {
+	return utf8.RuneCountInString(body[:byteOffset])
+}
+
+
+// storeChirpEntities records the chirp's hashtags and resolvable mentions so
+// they can be queried later. Mentions of unknown users are ignored.
+func storeChirpEntities(ctx context.Context, queries *database.Queries, chirp database.Chirp) error {
+	entities := parseEntities(chirp.Body)
+
+	for _, tag := range entities.uniqueTags() {
+		err := queries.CreateChirpTag(ctx, database.CreateChirpTagParams{
+			ChirpID: chirp.ID,
+			Tag: tag,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	mentionTexts := entities.mentionTexts()
+	if len(mentionTexts) == 0 {
+		return nil
+	}
+
+	var emails, handles []string
+	for _, text := range mentionTexts {
+		if strings.Contains(text, "@") {
+			emails = append(emails, text)
+		} else {
+			handles = append(handles, text)
+		}
+	}
+
+	usersByText := make(map[string]uuid.UUID)
+	if len(emails) > 0 {
+		users, err := queries.GetUsersWithEmails(ctx, emails)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			usersByText[user.Email] = user.ID
+		}
+	}
+	if len(handles) > 0 {
+		users, err := queries.GetUsersWithHandles(ctx, handles)
+		if err != nil {
+			return err
+		}
+		for _, user := range users {
+			usersByText[strings.ToLower(user.Handle)] = user.ID
+		}
+	}
+
+	// a user mentioned both ways is recorded under whichever came first
+	for _, text := range mentionTexts {
+		userID, ok := usersByText[text]
+		if !ok {
+			continue
+		}
+		err := queries.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID: chirp.ID,
+			UserID: userID,
+			MentionText: text,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
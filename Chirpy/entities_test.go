@@ -0,0 +1,107 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+
+func TestParseEntities(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		wantTags     []Hashtag
+		wantMentions []string
+	}{
+		{
+			name:         "no entities",
+			input:        "hello world",
+			wantTags:     []Hashtag{},
+			wantMentions: []string{},
+		},
+		{
+			name:         "hashtags are lowercased",
+			input:        "learning #Go and #sqlc",
+			wantTags:     []Hashtag{{Tag: "go", Start: 9, End: 12}, {Tag: "sqlc", Start: 17, End: 22}},
+			wantMentions: []string{},
+		},
+		{
+			name:         "hashtag inside a word is ignored",
+			input:        "c# and f#",
+			wantTags:     []Hashtag{},
+			wantMentions: []string{},
+		},
+		{
+			name:         "hashtag after punctuation",
+			input:        "(#chirpy)",
+			wantTags:     []Hashtag{{Tag: "chirpy", Start: 1, End: 8}},
+			wantMentions: []string{},
+		},
+		{
+			name:         "offsets count characters not bytes",
+			input:        "café #déjà",
+			wantTags:     []Hashtag{{Tag: "déjà", Start: 5, End: 10}},
+			wantMentions: []string{},
+		},
+		{
+			name:         "email and handle mentions",
+			input:        "hi @walt@example.com and @jesse!",
+			wantTags:     []Hashtag{},
+			wantMentions: []string{"walt@example.com", "jesse"},
+		},
+		{
+			name:         "plain email address is not a mention",
+			input:        "mail walt@example.com",
+			wantTags:     []Hashtag{},
+			wantMentions: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entities := parseEntities(tt.input)
+			if !reflect.DeepEqual(entities.Hashtags, tt.wantTags) {
+				t.Errorf("got hashtags %+v, want %+v", entities.Hashtags, tt.wantTags)
+			}
+			gotMentions := []string{}
+			for _, mention := range entities.Mentions {
+				gotMentions = append(gotMentions, mention.Text)
+			}
+			if !reflect.DeepEqual(gotMentions, tt.wantMentions) {
+				t.Errorf("got mentions %v, want %v", gotMentions, tt.wantMentions)
+			}
+		})
+	}
+}
+
+
+func TestResolveMentions(t *testing.T) {
+	walt := uuid.New()
+	entities := parseEntities("@walt@example.com meet @nobody")
+	entities.resolveMentions(map[string]uuid.UUID{"walt@example.com": walt})
+
+	if len(entities.Mentions) != 1 {
+		t.Fatalf("got %d mentions, want 1", len(entities.Mentions))
+	}
+	if entities.Mentions[0].UserID != walt || entities.Mentions[0].Start != 0 || entities.Mentions[0].End != 17 {
+		t.Errorf("unexpected mention %+v", entities.Mentions[0])
+	}
+}
+
+
+func TestResolveMentionsByHandle(t *testing.T) {
+	walt := uuid.New()
+	entities := parseEntities("@Walt and @walt again")
+	entities.resolveMentions(map[string]uuid.UUID{mentionKey("WALT"): walt})
+
+	if len(entities.Mentions) != 2 {
+		t.Fatalf("got %d mentions, want 2", len(entities.Mentions))
+	}
+	for _, mention := range entities.Mentions {
+		if mention.UserID != walt {
+			t.Errorf("unexpected mention %+v", mention)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/ratelimit"
+	"github.com/google/uuid"
+)
+
+// Entitlements are what a user's plan lets them do. Handlers consult these
+// rather than checking is_chirpy_red themselves.
+type Entitlements struct {
+	MaxChirpLength 		int
+	CanEditChirps 		bool
+	CanScheduleChirps 	bool
+	// how often the user may post or edit chirps
+	ChirpRate 			ratelimit.Rate
+}
+
+var freeEntitlements = Entitlements{
+	MaxChirpLength: maxChirpLength,
+	ChirpRate: ratelimit.Rate{Requests: 5, Per: time.Minute},
+}
+
+var chirpyRedEntitlements = Entitlements{
+	MaxChirpLength: 280,
+	CanEditChirps: true,
+	CanScheduleChirps: true,
+	ChirpRate: ratelimit.Rate{Requests: 30, Per: time.Minute},
+}
+
+
+func entitlementsFor(user database.User) Entitlements {
+	if user.IsChirpyRed {
+		return chirpyRedEntitlements
+	}
+	return freeEntitlements
+}
+
+
+func (cfg *apiConfig) userEntitlements(ctx context.Context, userID uuid.UUID) (Entitlements, error) {
+	user, err := cfg.dbQueries.GetUserWithID(ctx, userID)
+	if err != nil {
+		return Entitlements{}, err
+	}
+	return entitlementsFor(user), nil
+}
+
+
+// requireEntitlements looks up the authenticated user's entitlements. On
+// failure it writes the error response itself.
+func (cfg *apiConfig) requireEntitlements(writer http.ResponseWriter, request *http.Request, userID uuid.UUID) (Entitlements, bool) {
+	entitlements, err := cfg.userEntitlements(request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusUnauthorized, "User no longer exists", err)
+			return Entitlements{}, false
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
+		return Entitlements{}, false
+	}
+	return entitlements, true
+}
+
+
+// allowChirpWrite applies the user's chirp rate limit. When the limit is hit
+// it answers 429 with a Retry-After header.
+func (cfg *apiConfig) allowChirpWrite(writer http.ResponseWriter, userID uuid.UUID, entitlements Entitlements) bool {
+	allowed, wait := cfg.limiter.Allow("chirps:"+userID.String(), entitlements.ChirpRate)
+	if allowed {
+		return true
+	}
+
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	writer.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	responseError(writer, http.StatusTooManyRequests, fmt.Sprintf("Too many chirps, try again in %d seconds", retryAfter), nil)
+	return false
+}
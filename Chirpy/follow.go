@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+type Follow struct {
+	UserID 		uuid.UUID 	`json:"user_id"`
+	FollowedAt 	time.Time 	`json:"followed_at"`
+}
+
+
+func followPosition(follow Follow) (time.Time, uuid.UUID) {
+	return follow.FollowedAt, follow.UserID
+}
+
+
+func (cfg *apiConfig) followUser(writer http.ResponseWriter, request *http.Request) {
+	followerID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	followeeID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	if followerID == followeeID {
+		responseError(writer, http.StatusBadRequest, "Users cannot follow themselves", nil)
+		return
+	}
+
+	// following someone twice is a no-op
+	err = cfg.dbQueries.FollowUser(request.Context(), database.FollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23503" {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error following user", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+func (cfg *apiConfig) unfollowUser(writer http.ResponseWriter, request *http.Request) {
+	followerID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	followeeID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	err = cfg.dbQueries.UnfollowUser(request.Context(), database.UnfollowUserParams{
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error unfollowing user", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+func (cfg *apiConfig) getFollowers(writer http.ResponseWriter, request *http.Request) {
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var rows []database.Follow
+	if page.scanDescending() {
+		rows, err = cfg.dbQueries.ListFollowersDesc(request.Context(), database.ListFollowersDescParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		rows, err = cfg.dbQueries.ListFollowersAsc(request.Context(), database.ListFollowersAscParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching followers", err)
+		return
+	}
+
+	followers := make([]Follow, len(rows))
+	for i, row := range rows {
+		followers[i] = Follow{UserID: row.FollowerID, FollowedAt: row.CreatedAt}
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, followers, followPosition))
+}
+
+
+func (cfg *apiConfig) getFollowing(writer http.ResponseWriter, request *http.Request) {
+	userID, err := uuid.Parse(request.PathValue("userID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var rows []database.Follow
+	if page.scanDescending() {
+		rows, err = cfg.dbQueries.ListFollowingDesc(request.Context(), database.ListFollowingDescParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		rows, err = cfg.dbQueries.ListFollowingAsc(request.Context(), database.ListFollowingAscParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching followed users", err)
+		return
+	}
+
+	following := make([]Follow, len(rows))
+	for i, row := range rows {
+		following[i] = Follow{UserID: row.FolloweeID, FollowedAt: row.CreatedAt}
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, following, followPosition))
+}
+
+
+// getTimeline returns the authenticated user's home feed: their own chirps
+// merged with those of everyone they follow.
+func (cfg *apiConfig) getTimeline(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var dbChirps []database.Chirp
+	if page.scanDescending() {
+		dbChirps, err = cfg.dbQueries.ListTimelineDesc(request.Context(), database.ListTimelineDescParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		dbChirps, err = cfg.dbQueries.ListTimelineAsc(request.Context(), database.ListTimelineAscParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching timeline", err)
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, chirp := range dbChirps {
+		chirps[i] = chirpFromDB(chirp)
+	}
+
+	if err := cfg.decorateChirps(request.Context(), userID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, chirps, chirpPosition))
+}
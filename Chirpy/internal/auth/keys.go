@@ -0,0 +1,290 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// smaller RSA keys are refused when loading
+const minRSAKeyBits = 2048
+
+// Key is one asymmetric key in a KeySet. Keys without a private half can
+// only verify tokens, which is how retired keys are kept around.
+type Key struct {
+	ID 			string
+	method 		jwt.SigningMethod
+	private 	interface{}
+	public 		interface{}
+}
+
+// KeySet signs access tokens with one key and verifies them with any key
+// it holds, found by the token's kid header. Without asymmetric keys it
+// falls back to HS256 with a shared secret.
+type KeySet struct {
+	signing *Key
+	keys 	map[string]*Key
+	// verifies tokens without a kid, issued before keys were configured
+	secret 	string
+}
+
+// JWK is the public half of a key in JSON Web Key form.
+type JWK struct {
+	KeyType 	string `json:"kty"`
+	KeyID 		string `json:"kid"`
+	Use 		string `json:"use"`
+	Algorithm 	string `json:"alg"`
+	// RSA
+	Modulus 	string `json:"n,omitempty"`
+	Exponent 	string `json:"e,omitempty"`
+	// Ed25519
+	Curve 		string `json:"crv,omitempty"`
+	X 			string `json:"x,omitempty"`
+}
+
+// Claims are the claims in an access token. Scope is space separated, as in
+// RFC 9068; ClientID is only set on tokens issued to OAuth clients.
+type Claims struct {
+	jwt.RegisteredClaims
+	Scope 		string `json:"scope,omitempty"`
+	ClientID 	string `json:"client_id,omitempty"`
+}
+
+// JWKSet is the document served at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+
+// NewKeySet signs and verifies with the shared secret only.
+func NewKeySet(secret string) *KeySet {
+	return &KeySet{keys: map[string]*Key{}, secret: secret}
+}
+
+
+// LoadKeySet reads every .pem file in dir as a key whose ID is the file name
+// without the extension. Tokens are signed with signingKeyID, which must
+// be a private key; the rest only verify. secret may be empty, otherwise
+// HS256 tokens issued before the switch keep working until they expire.
+func LoadKeySet(dir, signingKeyID, secret string) (*KeySet, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	keySet := NewKeySet(secret)
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		keyID := strings.TrimSuffix(filepath.Base(path), ".pem")
+		key, err := ParseKey(keyID, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		keySet.keys[keyID] = key
+	}
+
+	signing, ok := keySet.keys[signingKeyID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", signingKeyID, dir)
+	}
+	if signing.private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", signingKeyID)
+	}
+	keySet.signing = signing
+
+	return keySet, nil
+}
+
+
+// ParseKey reads a PEM encoded RSA or Ed25519 key. Private keys may be PKCS#8
+// or PKCS#1, public keys PKIX.
+func ParseKey(keyID string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &Key{ID: keyID}
+	switch typed := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodRS256, typed, &typed.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.public = jwt.SigningMethodRS256, typed
+	case ed25519.PrivateKey:
+		key.method, key.private, key.public = jwt.SigningMethodEdDSA, typed, typed.Public()
+	case ed25519.PublicKey:
+		key.method, key.public = jwt.SigningMethodEdDSA, typed
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", parsed)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSAKeyBits {
+		return nil, fmt.Errorf("RSA keys must be at least %d bits", minRSAKeyBits)
+	}
+
+	return key, nil
+}
+
+
+// MakeJWT issues an access token for the user limited to scopes, signed with
+// the set's signing key and naming it in the kid header.
+func (keySet *KeySet) MakeJWT(userID uuid.UUID, scopes []string, expiresIn time.Duration) (string, error) {
+	return keySet.MakeClientJWT(userID, "", scopes, expiresIn)
+}
+
+
+// MakeClientJWT issues an access token the user granted to an OAuth client,
+// naming the client in the client_id claim.
+func (keySet *KeySet) MakeClientJWT(userID uuid.UUID, clientID string, scopes []string, expiresIn time.Duration) (string, error) {
+	if expiresIn <= 0 {
+		return "", fmt.Errorf("expiresIn duration must be positive")
+	}
+
+	if userID == uuid.Nil {
+		return "", fmt.Errorf("given user UUID cannot be nil")
+	}
+
+	if err := ValidateScopes(scopes); err != nil {
+		return "", err
+	}
+
+	claims := &Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer: "chirpy",
+			IssuedAt: jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+			Subject: userID.String(),
+		},
+		Scope: strings.Join(scopes, " "),
+		ClientID: clientID,
+	}
+
+	if keySet.signing == nil {
+		if keySet.secret == "" {
+			return "", fmt.Errorf("secret string cannot be empty")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(keySet.secret))
+	}
+
+	token := jwt.NewWithClaims(keySet.signing.method, claims)
+	token.Header["kid"] = keySet.signing.ID
+
+	return token.SignedString(keySet.signing.private)
+}
+
+
+// ParseJWT checks a token against the key its kid names and returns its
+// claims. The algorithm has to be the one that key is for, so a public key
+// can never be used as an HMAC secret.
+func (keySet *KeySet) ParseJWT(tokenString string) (*Claims, error) {
+	var claims Claims
+
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (interface{}, error) {
+		keyID, hasKeyID := token.Header["kid"].(string)
+		if !hasKeyID {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok || keySet.secret == "" {
+				return nil, fmt.Errorf("token has no key ID")
+			}
+			return []byte(keySet.secret), nil
+		}
+
+		key, ok := keySet.keys[keyID]
+		if !ok {
+			return nil, fmt.Errorf("unknown key ID %q", keyID)
+		}
+		if token.Method.Alg() != key.method.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return key.public, nil
+	}, jwt.WithExpirationRequired())
+	if err != nil {
+		return nil, err
+	}
+
+	return &claims, nil
+}
+
+
+// ValidateJWT checks a token like ParseJWT and returns the user it was issued
+// to and its scopes.
+func (keySet *KeySet) ValidateJWT(tokenString string) (uuid.UUID, []string, error) {
+	claims, err := keySet.ParseJWT(tokenString)
+	if err != nil {
+		return uuid.Nil, nil, err
+	}
+
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, nil, fmt.Errorf("invalid uuid, cannot proceed")
+	}
+
+	// tokens from before scopes existed were issued by a password login
+	if claims.Scope == "" {
+		return userID, AllScopes, nil
+	}
+	return userID, ParseScopes(claims.Scope), nil
+}
+
+
+// JWKS lists the public half of every key in the set, sorted by ID. The
+// shared secret is never included.
+func (keySet *KeySet) JWKS() JWKSet {
+	keyIDs := make([]string, 0, len(keySet.keys))
+	for keyID := range keySet.keys {
+		keyIDs = append(keyIDs, keyID)
+	}
+	sort.Strings(keyIDs)
+
+	jwks := JWKSet{Keys: []JWK{}}
+	for _, keyID := range keyIDs {
+		key := keySet.keys[keyID]
+		jwk := JWK{KeyID: key.ID, Use: "sig", Algorithm: key.method.Alg()}
+
+		switch public := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.KeyType = "RSA"
+			jwk.Modulus = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.Exponent = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.KeyType = "OKP"
+			jwk.Curve = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
@@ -0,0 +1,391 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+
+func writeKey(t *testing.T, dir, keyID, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(filepath.Join(dir, keyID+".pem"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+
+// writeTestKeys creates an Ed25519 key "ed-1", an RSA key "rsa-1" and a
+// public-only copy of the RSA key as "rsa-old".
+func writeTestKeys(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+
+	_, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(edPrivate)
+	writeKey(t, dir, "ed-1", "PRIVATE KEY", der)
+
+	rsaPrivate, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeKey(t, dir, "rsa-1", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(rsaPrivate))
+	der, _ = x509.MarshalPKIXPublicKey(&rsaPrivate.PublicKey)
+	writeKey(t, dir, "rsa-old", "PUBLIC KEY", der)
+
+	return dir
+}
+
+
+// makeLegacyJWT signs a token the way access tokens were issued before key
+// sets: HS256 with the shared secret, no kid and no scope claim.
+func makeLegacyJWT(userID uuid.UUID, secret string, expiresIn time.Duration) string {
+	claims := jwt.RegisteredClaims{
+		Issuer: "chirpy",
+		IssuedAt: jwt.NewNumericDate(time.Now()),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiresIn)),
+		Subject: userID.String(),
+	}
+	signed, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	return signed
+}
+
+
+func TestKeySetSignsAndVerifies(t *testing.T) {
+	dir := writeTestKeys(t)
+	userID := uuid.New()
+
+	for _, keyID := range []string{"ed-1", "rsa-1"} {
+		t.Run(keyID, func(t *testing.T) {
+			keySet, err := LoadKeySet(dir, keyID, "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			token, err := keySet.MakeJWT(userID, []string{ScopeChirpsRead}, time.Hour)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			got, scopes, err := keySet.ValidateJWT(token)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != userID {
+				t.Errorf("got user %v, want %v", got, userID)
+			}
+			if len(scopes) != 1 || scopes[0] != ScopeChirpsRead {
+				t.Errorf("got scopes %v, want [%s]", scopes, ScopeChirpsRead)
+			}
+		})
+	}
+}
+
+
+func TestKeySetRotation(t *testing.T) {
+	dir := writeTestKeys(t)
+	userID := uuid.New()
+
+	old, err := LoadKeySet(dir, "rsa-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := old.MakeJWT(userID, AllScopes, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the RSA key is still in the directory, so its tokens stay valid
+	rotated, err := LoadKeySet(dir, "ed-1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rotated.ValidateJWT(token); err != nil {
+		t.Errorf("token signed with the previous key was rejected: %v", err)
+	}
+}
+
+
+func TestLoadKeySetRejectsPublicSigningKey(t *testing.T) {
+	dir := writeTestKeys(t)
+	if _, err := LoadKeySet(dir, "rsa-old", ""); err == nil {
+		t.Errorf("expected an error for a signing key without a private half")
+	}
+	if _, err := LoadKeySet(dir, "missing", ""); err == nil {
+		t.Errorf("expected an error for an unknown signing key")
+	}
+}
+
+
+func TestKeySetValidateJWTRejects(t *testing.T) {
+	dir := writeTestKeys(t)
+	keySet, err := LoadKeySet(dir, "ed-1", "legacy-secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID := uuid.New()
+
+	// issued before scopes, so it has no scope claim
+	legacy := makeLegacyJWT(userID, "legacy-secret", time.Hour)
+	got, scopes, err := keySet.ValidateJWT(legacy)
+	if err != nil || got != userID {
+		t.Errorf("legacy HS256 token was rejected: %v", err)
+	}
+	if len(scopes) != len(AllScopes) {
+		t.Errorf("legacy token got scopes %v, want all of them", scopes)
+	}
+
+	claims := jwt.RegisteredClaims{
+		Subject: userID.String(),
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}
+
+	tests := []struct {
+		name 	string
+		token 	func() string
+	}{
+		{
+			name: "unknown key ID",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+				token.Header["kid"] = "nope"
+				signed, _ := token.SignedString([]byte("legacy-secret"))
+				return signed
+			},
+		},
+		{
+			name: "HMAC token naming an asymmetric key",
+			token: func() string {
+				token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+				token.Header["kid"] = "ed-1"
+				signed, _ := token.SignedString([]byte("legacy-secret"))
+				return signed
+			},
+		},
+		{
+			name: "HMAC token with the wrong secret",
+			token: func() string {
+				return makeLegacyJWT(userID, "other-secret", time.Hour)
+			},
+		},
+		{
+			name: "expired legacy token",
+			token: func() string {
+				return makeLegacyJWT(userID, "legacy-secret", -time.Hour)
+			},
+		},
+		{
+			name: "invalid token format",
+			token: func() string { return "not.a.token" },
+		},
+		{
+			name: "expired token",
+			token: func() string {
+				signed, _ := keySet.MakeJWT(userID, AllScopes, time.Nanosecond)
+				time.Sleep(time.Millisecond)
+				return signed
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, _, err := keySet.ValidateJWT(test.token()); err == nil {
+				t.Errorf("expected the token to be rejected")
+			}
+		})
+	}
+}
+
+
+func TestKeySetMakeJWT(t *testing.T) {
+	tests := []struct {
+		name 			string
+		userID 			uuid.UUID
+		secretString 	string
+		expiresIn 		time.Duration
+		wantErr			bool
+	}{
+		{
+			name:			"valid token",
+			userID:			uuid.New(),
+			secretString:	"jwt-test-secret",
+			expiresIn:		time.Hour,
+			wantErr:		false,
+		},
+		{
+			name:			"valid token short duration",
+			userID:			uuid.New(),
+			secretString:	"jwt-test-secret",
+			expiresIn:		time.Second,
+			wantErr:		false,
+		},
+		{
+			name:			"empty secret",
+			userID:			uuid.New(),
+			secretString:	"",
+			expiresIn:		time.Hour,
+			wantErr:		true,
+		},
+		{
+			name:         "zero duration",
+			userID:       uuid.New(),
+			secretString: "secret123",
+			expiresIn:    0,
+			wantErr:      true,
+		},
+		{
+			name:         "negative duration",
+			userID:       uuid.New(),
+			secretString: "secret123",
+			expiresIn:    -time.Hour,
+			wantErr:      true,
+		},
+		{
+			name:         "nil UUID",
+			userID:       uuid.Nil,
+			secretString: "secret123",
+			expiresIn:    time.Hour,
+			wantErr:      true,
+		},
+
+	}
+
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			token, err := NewKeySet(testCase.secretString).MakeJWT(testCase.userID, AllScopes, testCase.expiresIn)
+			if (err != nil) != testCase.wantErr {
+				t.Errorf("MakeJWT() error = %v, wanted Err %v", err, testCase.wantErr)
+				return
+			}
+
+			if testCase.wantErr {
+				return
+			}
+
+
+			if len(token) <= 0 {
+				t.Errorf("MakeJWT() returned an empty token")
+				return
+			}
+			parsedToken, err := jwt.ParseWithClaims(token, &jwt.RegisteredClaims{}, func(tkn *jwt.Token) (interface{}, error) {
+				return []byte(testCase.secretString), nil
+			})
+			if err != nil {
+				t.Errorf("Could not parse the token returned from MakeJWT(): %v", err)
+				return
+			}
+
+			claims, ok := parsedToken.Claims.(*jwt.RegisteredClaims)
+			if !ok {
+				t.Errorf("Could not get claims for the returned token")
+				return
+			}
+
+			if claims.Issuer != "chirpy" {
+				t.Errorf("Expected issuer to be 'chirpy', got %v", claims.Issuer)
+			}
+			if claims.Subject != testCase.userID.String() {
+				t.Errorf("Expected subject to be %v, got %v", testCase.userID, claims.Subject)
+			}
+			expectedExp := time.Now().Add(testCase.expiresIn)
+			epsilon := time.Second
+
+			if claims.ExpiresAt == nil {
+				t.Error("ExpiresAt claim is nil")
+			} else {
+				diff := claims.ExpiresAt.Time.Sub(expectedExp)
+				if diff < -epsilon || diff > epsilon {
+					t.Errorf("ExpiresAt above tolerance, expected: %v, got: %v", expectedExp, claims.ExpiresAt.Time)
+				}
+			}
+
+		})
+	}
+}
+
+
+func TestKeySetMakeJWTRequiresScopes(t *testing.T) {
+	keySet := NewKeySet("secret")
+	userID := uuid.New()
+
+	if _, err := keySet.MakeJWT(userID, nil, time.Hour); err == nil {
+		t.Errorf("expected an error for a token without scopes")
+	}
+	if _, err := keySet.MakeJWT(userID, []string{"admin"}, time.Hour); err == nil {
+		t.Errorf("expected an error for an unknown scope")
+	}
+
+	token, err := keySet.MakeJWT(userID, []string{ScopeChirpsRead, ScopeAccount}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, scopes, err := keySet.ValidateJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !HasScope(scopes, ScopeAccount) || HasScope(scopes, ScopeChirpsWrite) {
+		t.Errorf("got scopes %v, want [%s %s]", scopes, ScopeChirpsRead, ScopeAccount)
+	}
+}
+
+
+func TestKeySetMakeClientJWT(t *testing.T) {
+	keySet := NewKeySet("secret")
+	userID := uuid.New()
+
+	token, err := keySet.MakeClientJWT(userID, "client-1", []string{ScopeChirpsRead}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := keySet.ParseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.ClientID != "client-1" || claims.Subject != userID.String() || claims.Scope != ScopeChirpsRead {
+		t.Errorf("got claims %+v", claims)
+	}
+}
+
+
+func TestKeySetJWKS(t *testing.T) {
+	dir := writeTestKeys(t)
+	keySet, err := LoadKeySet(dir, "ed-1", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := keySet.JWKS()
+	if len(jwks.Keys) != 3 {
+		t.Fatalf("got %d keys, want 3", len(jwks.Keys))
+	}
+
+	ed, rsaKey := jwks.Keys[0], jwks.Keys[1]
+	if ed.KeyID != "ed-1" || ed.KeyType != "OKP" || ed.Algorithm != "EdDSA" || ed.X == "" {
+		t.Errorf("unexpected Ed25519 JWK %+v", ed)
+	}
+	if rsaKey.KeyID != "rsa-1" || rsaKey.KeyType != "RSA" || rsaKey.Algorithm != "RS256" || rsaKey.Exponent != "AQAB" {
+		t.Errorf("unexpected RSA JWK %+v", rsaKey)
+	}
+	if jwks.Keys[2].Modulus != rsaKey.Modulus {
+		t.Errorf("public-only copy of the RSA key has a different modulus")
+	}
+
+	if len(NewKeySet("secret").JWKS().Keys) != 0 {
+		t.Errorf("a secret-only key set should not publish any keys")
+	}
+}
@@ -0,0 +1,49 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+// the example from RFC 7636 appendix B
+const (
+	testCodeVerifier 	= "dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"
+	testCodeChallenge 	= "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM"
+)
+
+
+func TestVerifyPKCE(t *testing.T) {
+	tests := []struct {
+		name 		string
+		verifier 	string
+		challenge 	string
+		want 		bool
+	}{
+		{name: "RFC example", verifier: testCodeVerifier, challenge: testCodeChallenge, want: true},
+		{name: "wrong verifier", verifier: strings.Repeat("a", 43), challenge: testCodeChallenge, want: false},
+		{name: "verifier too short", verifier: "abc", challenge: testCodeChallenge, want: false},
+		{name: "verifier with invalid characters", verifier: strings.Repeat("a", 42) + "+", challenge: testCodeChallenge, want: false},
+		{name: "plain challenge", verifier: testCodeVerifier, challenge: testCodeVerifier, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := VerifyPKCE(test.verifier, test.challenge); got != test.want {
+				t.Errorf("got %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+
+func TestValidateCodeChallenge(t *testing.T) {
+	if err := ValidateCodeChallenge(testCodeChallenge, "S256"); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := ValidateCodeChallenge(testCodeChallenge, "plain"); err == nil {
+		t.Errorf("expected an error for the plain method")
+	}
+	if err := ValidateCodeChallenge("short", "S256"); err == nil {
+		t.Errorf("expected an error for a malformed challenge")
+	}
+}
@@ -0,0 +1,50 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+)
+
+// what an access token or API key is allowed to do
+const (
+	ScopeChirpsRead 	= "chirps:read"
+	ScopeChirpsWrite 	= "chirps:write"
+	// profile, email, password, sessions and API keys
+	ScopeAccount 		= "account"
+)
+
+// AllScopes is what a password login grants.
+var AllScopes = []string{ScopeChirpsRead, ScopeChirpsWrite, ScopeAccount}
+
+
+// ValidateScopes checks that scopes is a non-empty list of known scopes.
+func ValidateScopes(scopes []string) error {
+	if len(scopes) == 0 {
+		return fmt.Errorf("At least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !HasScope(AllScopes, scope) {
+			return fmt.Errorf("Unknown scope %q", scope)
+		}
+	}
+
+	return nil
+}
+
+
+func HasScope(scopes []string, scope string) bool {
+	for _, granted := range scopes {
+		if granted == scope {
+			return true
+		}
+	}
+	return false
+}
+
+
+// ParseScopes splits a space separated scope string, as used by the scope
+// claim and OAuth.
+func ParseScopes(scope string) []string {
+	return strings.Fields(scope)
+}
@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrStaleSignature 	= errors.New("webhook timestamp is outside the allowed tolerance")
+)
+
+
+// MakeWebhookSignature signs a webhook body the way Polka does: an HMAC-SHA256
+// over "<unix timestamp>.<body>", sent as "t=<timestamp>,v1=<hex digest>".
+func MakeWebhookSignature(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", unix, webhookDigest(secret, unix, body))
+}
+
+
+// ValidateWebhookSignature checks a signature header against the body. The
+// timestamp has to be within tolerance of now in either direction so that
+// captured requests can't be replayed later on.
+func ValidateWebhookSignature(header, secret string, body []byte, now time.Time, tolerance time.Duration) error {
+	if len(secret) <= 0 {
+		return fmt.Errorf("secret string cannot be empty")
+	}
+
+	var unix string
+	var signatures []string
+	for _, part := range strings.Split(header, ",") {
+		key, value, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found {
+			return fmt.Errorf("Malformed signature header")
+		}
+		switch key {
+		case "t":
+			unix = value
+		case "v1":
+			signatures = append(signatures, value)
+		}
+	}
+
+	if unix == "" || len(signatures) == 0 {
+		return fmt.Errorf("Malformed signature header")
+	}
+
+	seconds, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil {
+		return fmt.Errorf("Malformed signature timestamp: %w", err)
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > tolerance || age < -tolerance {
+		return ErrStaleSignature
+	}
+
+	expected := []byte(webhookDigest(secret, unix, body))
+	// several v1 entries are allowed while a secret is being rotated
+	for _, signature := range signatures {
+		if hmac.Equal(expected, []byte(signature)) {
+			return nil
+		}
+	}
+
+	return ErrInvalidSignature
+}
+
+
+func webhookDigest(secret, unix string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
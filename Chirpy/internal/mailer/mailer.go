@@ -0,0 +1,146 @@
+package mailer
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/mail"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var ErrInvalidHeader = errors.New("header values cannot contain line breaks")
+
+// Message is a plain text email to a single recipient.
+type Message struct {
+	To 		string
+	Subject string
+	Body 	string
+}
+
+// Mailer delivers messages. Implementations should be safe for concurrent use.
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+
+// encode renders the message as an RFC 5322 email.
+func (message Message) encode(from string, date time.Time) ([]byte, error) {
+	for _, value := range []string{from, message.To, message.Subject} {
+		if strings.ContainsAny(value, "\r\n") {
+			return nil, ErrInvalidHeader
+		}
+	}
+
+	var encoded bytes.Buffer
+	fmt.Fprintf(&encoded, "From: %s\r\n", from)
+	fmt.Fprintf(&encoded, "To: %s\r\n", message.To)
+	fmt.Fprintf(&encoded, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&encoded, "Date: %s\r\n", date.Format(time.RFC1123Z))
+	encoded.WriteString("MIME-Version: 1.0\r\n")
+	encoded.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	encoded.WriteString("\r\n")
+	encoded.WriteString(strings.ReplaceAll(strings.ReplaceAll(message.Body, "\r\n", "\n"), "\n", "\r\n"))
+	return encoded.Bytes(), nil
+}
+
+
+// SMTPMailer sends mail through an SMTP server, using STARTTLS when the
+// server offers it.
+type SMTPMailer struct {
+	addr 	string
+	from 	string
+	// the bare address from is sent with in the SMTP envelope
+	sender 	string
+	auth 	smtp.Auth
+}
+
+
+// NewSMTPMailer sends as from, which may include a display name, through the
+// server at addr (host:port). PLAIN authentication is only used when a
+// username is given.
+func NewSMTPMailer(addr, from, username, password string) *SMTPMailer {
+	mailer := &SMTPMailer{addr: addr, from: from, sender: from}
+	if address, err := mail.ParseAddress(from); err == nil {
+		mailer.sender = address.Address
+	}
+	if username != "" {
+		host := addr
+		if index := strings.LastIndex(addr, ":"); index >= 0 {
+			host = addr[:index]
+		}
+		mailer.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return mailer
+}
+
+
+// Send delivers the message. net/smtp has no context support, so ctx is only
+// checked before connecting.
+func (mailer *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	encoded, err := message.encode(mailer.from, time.Now())
+	if err != nil {
+		return err
+	}
+	return smtp.SendMail(mailer.addr, mailer.auth, mailer.sender, []string{message.To}, encoded)
+}
+
+
+// FileMailer writes every message to its own .eml file instead of sending
+// it, for local development.
+type FileMailer struct {
+	dir 	string
+	from 	string
+}
+
+
+func NewFileMailer(dir, from string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileMailer{dir: dir, from: from}, nil
+}
+
+
+func (mailer *FileMailer) Send(ctx context.Context, message Message) error {
+	now := time.Now()
+	encoded, err := message.encode(mailer.from, now)
+	if err != nil {
+		return err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", now.UTC().Format("20060102T150405"), hex.EncodeToString(suffix))
+
+	return os.WriteFile(filepath.Join(mailer.dir, name), encoded, 0o600)
+}
+
+
+// LogMailer prints messages to the standard logger instead of sending them.
+type LogMailer struct {
+	From string
+}
+
+
+func (mailer LogMailer) Send(ctx context.Context, message Message) error {
+	encoded, err := message.encode(mailer.From, time.Now())
+	if err != nil {
+		return err
+	}
+	log.Printf("Outgoing mail:\n%s", encoded)
+	return nil
+}
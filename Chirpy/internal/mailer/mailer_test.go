@@ -0,0 +1,63 @@
+package mailer
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+
+func TestEncode(t *testing.T) {
+	message := Message{To: "walt@example.com", Subject: "Reset your password", Body: "line one\nline two"}
+	encoded, err := message.encode("chirpy@example.com", time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	text := string(encoded)
+	for _, want := range []string{
+		"From: chirpy@example.com\r\n",
+		"To: walt@example.com\r\n",
+		"Subject: Reset your password\r\n",
+		"\r\n\r\nline one\r\nline two",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("encoded message is missing %q:\n%s", want, text)
+		}
+	}
+}
+
+
+func TestEncodeRejectsHeaderInjection(t *testing.T) {
+	message := Message{To: "walt@example.com\r\nBcc: everyone@example.com", Subject: "hi"}
+	if _, err := message.encode("chirpy@example.com", time.Now()); !errors.Is(err, ErrInvalidHeader) {
+		t.Errorf("got %v, want ErrInvalidHeader", err)
+	}
+}
+
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir, "chirpy@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = mailer.Send(context.Background(), Message{To: "walt@example.com", Subject: "Hello", Body: "token: abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.eml"))
+	if len(files) != 1 {
+		t.Fatalf("got %d files, want 1", len(files))
+	}
+	data, _ := os.ReadFile(files[0])
+	if !strings.Contains(string(data), "token: abc") {
+		t.Errorf("message body was not written:\n%s", data)
+	}
+}
@@ -0,0 +1,138 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+
+	// register the decoders for every accepted upload type
+	_ "image/gif"
+	_ "image/jpeg"
+)
+
+const (
+	// MaxPixels guards against decompression bombs
+	MaxPixels = 40_000_000
+	// ThumbnailSize is the longest edge of a generated thumbnail
+	ThumbnailSize = 320
+)
+
+// extensions for the image types we accept, keyed by sniffed content type
+var allowedTypes = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+// Image is an uploaded picture after validation.
+type Image struct {
+	ContentType string
+	Extension 	string
+	Width 		int
+	Height 		int
+	decoded 	image.Image
+}
+
+
+// DecodeImage sniffs the upload's real content type, rejecting anything
+// that isn't a supported image, and decodes it.
+func DecodeImage(data []byte) (Image, error) {
+	contentType := http.DetectContentType(data)
+	extension, ok := allowedTypes[contentType]
+	if !ok {
+		return Image{}, fmt.Errorf("unsupported file type %q", contentType)
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("invalid image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || config.Width*config.Height > MaxPixels {
+		return Image{}, fmt.Errorf("image dimensions %dx%d are not allowed", config.Width, config.Height)
+	}
+
+	decoded, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return Image{}, fmt.Errorf("invalid image: %w", err)
+	}
+
+	return Image{
+		ContentType: contentType,
+		Extension: extension,
+		Width: config.Width,
+		Height: config.Height,
+		decoded: decoded,
+	}, nil
+}
+
+
+// Thumbnail scales the image down to fit a ThumbnailSize square and encodes
+// it as PNG. Images that already fit are only re-encoded.
+func (img Image) Thumbnail() ([]byte, error) {
+	width, height := fitWithin(img.Width, img.Height, ThumbnailSize)
+	scaled := scaleDown(img.decoded, width, height)
+
+	var encoded bytes.Buffer
+	if err := png.Encode(&encoded, scaled); err != nil {
+		return nil, err
+	}
+	return encoded.Bytes(), nil
+}
+
+
+func fitWithin(width, height, size int) (int, int) {
+	if width <= size && height <= size {
+		return width, height
+	}
+	if width >= height {
+		return size, max(1, height*size/width)
+	}
+	return max(1, width*size/height), size
+}
+
+
+// scaleDown resizes src with a box filter: every destination pixel is the
+// average of the source pixels it covers.
+func scaleDown(src image.Image, width, height int) image.Image {
+	bounds := src.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+
+	if bounds.Dx() == width && bounds.Dy() == height {
+		draw.Draw(dst, dst.Bounds(), src, bounds.Min, draw.Src)
+		return dst
+	}
+
+	for y := 0; y < height; y++ {
+		y0 := bounds.Min.Y + y*bounds.Dy()/height
+		y1 := max(y0+1, bounds.Min.Y+(y+1)*bounds.Dy()/height)
+		for x := 0; x < width; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/width
+			x1 := max(x0+1, bounds.Min.X+(x+1)*bounds.Dx()/width)
+
+			var r, g, b, a, count uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pixel := color.NRGBA64Model.Convert(src.At(sx, sy)).(color.NRGBA64)
+					r += uint64(pixel.R)
+					g += uint64(pixel.G)
+					b += uint64(pixel.B)
+					a += uint64(pixel.A)
+					count++
+				}
+			}
+
+			dst.Set(x, y, color.NRGBA64{
+				R: uint16(r / count),
+				G: uint16(g / count),
+				B: uint16(b / count),
+				A: uint16(a / count),
+			})
+		}
+	}
+
+	return dst
+}
@@ -0,0 +1,84 @@
+package media
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, color.NRGBA{R: 200, G: 100, B: 50, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+
+func TestDecodeImage(t *testing.T) {
+	img, err := DecodeImage(encodePNG(t, 40, 20))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.ContentType != "image/png" || img.Extension != ".png" {
+		t.Errorf("got type %q ext %q", img.ContentType, img.Extension)
+	}
+	if img.Width != 40 || img.Height != 20 {
+		t.Errorf("got %dx%d, want 40x20", img.Width, img.Height)
+	}
+
+	if _, err := DecodeImage([]byte("<html><script>alert(1)</script></html>")); err == nil {
+		t.Errorf("expected html upload to be rejected")
+	}
+}
+
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		name       string
+		width      int
+		height     int
+		wantWidth  int
+		wantHeight int
+	}{
+		{name: "landscape", width: 800, height: 400, wantWidth: ThumbnailSize, wantHeight: ThumbnailSize / 2},
+		{name: "portrait", width: 400, height: 800, wantWidth: ThumbnailSize / 2, wantHeight: ThumbnailSize},
+		{name: "already small", width: 50, height: 30, wantWidth: 50, wantHeight: 30},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := DecodeImage(encodePNG(t, tt.width, tt.height))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			thumbnail, err := img.Thumbnail()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			decoded, err := png.Decode(bytes.NewReader(thumbnail))
+			if err != nil {
+				t.Fatalf("thumbnail is not a png: %v", err)
+			}
+			bounds := decoded.Bounds()
+			if bounds.Dx() != tt.wantWidth || bounds.Dy() != tt.wantHeight {
+				t.Errorf("got %dx%d, want %dx%d", bounds.Dx(), bounds.Dy(), tt.wantWidth, tt.wantHeight)
+			}
+			if r, g, b, _ := decoded.At(0, 0).RGBA(); r>>8 != 200 || g>>8 != 100 || b>>8 != 50 {
+				t.Errorf("thumbnail colour changed: %d %d %d", r>>8, g>>8, b>>8)
+			}
+		})
+	}
+}
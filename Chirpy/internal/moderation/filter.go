@@ -0,0 +1,203 @@
+package moderation
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Policy decides what happens to a banned word found in a chirp.
+type Policy string
+
+const (
+	// PolicyMask swaps the word for a fixed "****"
+	PolicyMask Policy = "mask"
+	// PolicyRedact swaps every character of the word for "*"
+	PolicyRedact Policy = "redact"
+	// PolicyReplace swaps the word for the rule's Replacement
+	PolicyReplace Policy = "replace"
+	// PolicyReject refuses the whole text
+	PolicyReject Policy = "reject"
+)
+
+const maskString = "****"
+
+var ErrRejected = errors.New("text contains a rejected word")
+
+type Rule struct {
+	Word 		string 	`json:"word"`
+	Policy 		Policy 	`json:"policy"`
+	Replacement string 	`json:"replacement,omitempty"`
+}
+
+// Filter censors banned words in text. Its rules can be swapped at runtime
+// and it is safe for concurrent use.
+type Filter struct {
+	mu 		sync.RWMutex
+	rules 	map[string]Rule
+}
+
+// DefaultRules is the word list Chirpy has always shipped with.
+var DefaultRules = []Rule{
+	{Word: "kerfuffle", Policy: PolicyMask},
+	{Word: "sharbert", Policy: PolicyMask},
+	{Word: "fornax", Policy: PolicyMask},
+}
+
+
+func NewFilter(rules []Rule) (*Filter, error) {
+	filter := &Filter{}
+	if err := filter.SetRules(rules); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+
+// SetRules replaces every rule in the filter. Nothing changes if any of the
+// given rules is invalid.
+func (filter *Filter) SetRules(rules []Rule) error {
+	ruleMap := make(map[string]Rule, len(rules))
+	for _, rule := range rules {
+		validated, err := ValidateRule(rule)
+		if err != nil {
+			return err
+		}
+		ruleMap[validated.Word] = validated
+	}
+
+	filter.mu.Lock()
+	defer filter.mu.Unlock()
+	filter.rules = ruleMap
+	return nil
+}
+
+
+// Rules returns a snapshot of the current rules, sorted by word.
+func (filter *Filter) Rules() []Rule {
+	filter.mu.RLock()
+	defer filter.mu.RUnlock()
+
+	rules := make([]Rule, 0, len(filter.rules))
+	for _, rule := range filter.rules {
+		rules = append(rules, rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Word < rules[j].Word
+	})
+	return rules
+}
+
+
+// Clean censors every banned word in text according to its rule. Words are
+// matched whole, ignoring case, accents and surrounding punctuation, so
+// "KERFUFFLE!" is caught while "kerfuffles" is not. If any matched rule uses
+// PolicyReject, ErrRejected is returned instead.
+func (filter *Filter) Clean(text string) (string, error) {
+	filter.mu.RLock()
+	defer filter.mu.RUnlock()
+
+	var cleaned strings.Builder
+	cleaned.Grow(len(text))
+
+	wordStart := -1
+	flush := func(end int) error {
+		if wordStart < 0 {
+			return nil
+		}
+		word := text[wordStart:end]
+		wordStart = -1
+
+		rule, banned := filter.rules[Normalize(word)]
+		if !banned {
+			cleaned.WriteString(word)
+			return nil
+		}
+
+		switch rule.Policy {
+		case PolicyReject:
+			return fmt.Errorf("%w: %q", ErrRejected, word)
+		case PolicyRedact:
+			cleaned.WriteString(strings.Repeat("*", len([]rune(word))))
+		case PolicyReplace:
+			cleaned.WriteString(rule.Replacement)
+		default:
+			cleaned.WriteString(maskString)
+		}
+		return nil
+	}
+
+	for index, char := range text {
+		if isWordRune(char) {
+			if wordStart < 0 {
+				wordStart = index
+			}
+			continue
+		}
+		if err := flush(index); err != nil {
+			return "", err
+		}
+		cleaned.WriteRune(char)
+	}
+	if err := flush(len(text)); err != nil {
+		return "", err
+	}
+
+	return cleaned.String(), nil
+}
+
+
+// Normalize folds a word into the form rules are keyed by: compatibility
+// characters decomposed, accents dropped and lower case.
+func Normalize(word string) string {
+	folder := transform.Chain(norm.NFKD, runes.Remove(runes.In(unicode.Mn)), norm.NFC)
+	folded, _, err := transform.String(folder, word)
+	if err != nil {
+		folded = word
+	}
+	return strings.ToLower(folded)
+}
+
+
+// ValidateRule normalizes the rule's word and fills in the default policy.
+func ValidateRule(rule Rule) (Rule, error) {
+	rule.Word = Normalize(strings.TrimSpace(rule.Word))
+	if rule.Word == "" {
+		return Rule{}, fmt.Errorf("banned word cannot be empty")
+	}
+	for _, char := range rule.Word {
+		if !isWordRune(char) {
+			return Rule{}, fmt.Errorf("banned word %q must be a single word", rule.Word)
+		}
+	}
+
+	switch rule.Policy {
+	case "":
+		rule.Policy = PolicyMask
+	case PolicyMask, PolicyRedact, PolicyReject:
+	case PolicyReplace:
+		if rule.Replacement == "" {
+			return Rule{}, fmt.Errorf("policy %q needs a replacement", PolicyReplace)
+		}
+	default:
+		return Rule{}, fmt.Errorf("unknown policy %q", rule.Policy)
+	}
+
+	if rule.Policy != PolicyReplace {
+		rule.Replacement = ""
+	}
+
+	return rule, nil
+}
+
+
+func isWordRune(char rune) bool {
+	return unicode.IsLetter(char) || unicode.IsNumber(char) || unicode.Is(unicode.Mn, char)
+}
@@ -0,0 +1,146 @@
+package moderation
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+
+func TestCleanPolicies(t *testing.T) {
+	filter, err := NewFilter([]Rule{
+		{Word: "kerfuffle"},
+		{Word: "sharbert", Policy: PolicyRedact},
+		{Word: "fornax", Policy: PolicyReplace, Replacement: "[redacted]"},
+		{Word: "Grawlix", Policy: PolicyReject},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+		wantErr  error
+	}{
+		{
+			name:     "mask is the default policy",
+			input:    "what a kerfuffle.",
+			expected: "what a ****.",
+		},
+		{
+			name:     "redact keeps the length",
+			input:    "Sharbert, please",
+			expected: "********, please",
+		},
+		{
+			name:     "replace uses the replacement",
+			input:    "(fornax)",
+			expected: "([redacted])",
+		},
+		{
+			name:    "reject refuses the text",
+			input:   "such grawlix!",
+			wantErr: ErrRejected,
+		},
+		{
+			name:     "substrings are left alone",
+			input:    "kerfuffles",
+			expected: "kerfuffles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := filter.Clean(tt.input)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("got %q, want %q", result, tt.expected)
+			}
+		})
+	}
+}
+
+
+func TestValidateRule(t *testing.T) {
+	tests := []struct {
+		name    string
+		rule    Rule
+		want    Rule
+		wantErr bool
+	}{
+		{
+			name: "word is normalized",
+			rule: Rule{Word: "  Fórnax "},
+			want: Rule{Word: "fornax", Policy: PolicyMask},
+		},
+		{
+			name: "replacement dropped for other policies",
+			rule: Rule{Word: "fornax", Policy: PolicyRedact, Replacement: "x"},
+			want: Rule{Word: "fornax", Policy: PolicyRedact},
+		},
+		{
+			name:    "empty word",
+			rule:    Rule{Word: " "},
+			wantErr: true,
+		},
+		{
+			name:    "more than one word",
+			rule:    Rule{Word: "two words"},
+			wantErr: true,
+		},
+		{
+			name:    "replace without replacement",
+			rule:    Rule{Word: "fornax", Policy: PolicyReplace},
+			wantErr: true,
+		},
+		{
+			name:    "unknown policy",
+			rule:    Rule{Word: "fornax", Policy: "shout"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateRule(tt.rule)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+
+func TestFileSource(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "words.txt")
+	contents := "# banned words\nkerfuffle\n\nsharbert, redact\nfornax,replace,[bleep]\n"
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := FileSource{Path: path}.LoadRules(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sort.Slice(rules, func(i, j int) bool { return rules[i].Word < rules[j].Word })
+	want := []Rule{
+		{Word: "fornax", Policy: PolicyReplace, Replacement: "[bleep]"},
+		{Word: "kerfuffle", Policy: PolicyMask},
+		{Word: "sharbert", Policy: PolicyRedact},
+	}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("got %+v, want %+v", rules, want)
+	}
+}
@@ -0,0 +1,63 @@
+package moderation
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Source is where a Filter gets its rules from.
+type Source interface {
+	LoadRules(ctx context.Context) ([]Rule, error)
+}
+
+// FileSource reads rules from a plain text file with one rule per line:
+//
+//	word[,policy[,replacement]]
+//
+// Blank lines and lines starting with # are skipped.
+type FileSource struct {
+	Path string
+}
+
+
+func (source FileSource) LoadRules(ctx context.Context) ([]Rule, error) {
+	file, err := os.Open(source.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	rules := []Rule{}
+	scanner := bufio.NewScanner(file)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.SplitN(line, ",", 3)
+		rule := Rule{Word: fields[0]}
+		if len(fields) > 1 {
+			rule.Policy = Policy(strings.TrimSpace(fields[1]))
+		}
+		if len(fields) > 2 {
+			rule.Replacement = strings.TrimSpace(fields[2])
+		}
+
+		validated, err := ValidateRule(rule)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", source.Path, lineNumber, err)
+		}
+		rules = append(rules, validated)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,89 @@
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+// how often idle buckets are dropped from memory
+const sweepInterval = 10 * time.Minute
+
+// Rate allows Requests requests every Per, in bursts of up to Requests.
+type Rate struct {
+	Requests 	int
+	Per 		time.Duration
+}
+
+type bucket struct {
+	tokens 	float64
+	updated time.Time
+}
+
+// Limiter is an in-memory token bucket limiter keyed by arbitrary strings.
+// Each call says which rate applies, so a key's allowance can change between
+// calls, e.g. when a user changes plans.
+type Limiter struct {
+	mu 			sync.Mutex
+	buckets 	map[string]*bucket
+	lastSweep 	time.Time
+	now 		func() time.Time
+}
+
+
+func NewLimiter() *Limiter {
+	return &Limiter{
+		buckets: make(map[string]*bucket),
+		lastSweep: time.Now(),
+		now: time.Now,
+	}
+}
+
+
+// Allow takes a token from key's bucket. When the bucket is empty it returns
+// false along with how long the caller has to wait for the next token.
+func (limiter *Limiter) Allow(key string, rate Rate) (bool, time.Duration) {
+	limiter.mu.Lock()
+	defer limiter.mu.Unlock()
+
+	now := limiter.now()
+	limiter.sweep(now)
+
+	capacity := float64(rate.Requests)
+	perToken := rate.Per / time.Duration(rate.Requests)
+
+	current, ok := limiter.buckets[key]
+	if !ok {
+		current = &bucket{tokens: capacity, updated: now}
+		limiter.buckets[key] = current
+	}
+
+	current.tokens += float64(now.Sub(current.updated)) / float64(perToken)
+	if current.tokens > capacity {
+		current.tokens = capacity
+	}
+	current.updated = now
+
+	if current.tokens < 1 {
+		wait := time.Duration((1 - current.tokens) * float64(perToken))
+		return false, wait
+	}
+
+	current.tokens--
+	return true, 0
+}
+
+
+// sweep forgets buckets that haven't been touched for a while. By then they
+// would have refilled anyway, so dropping them changes nothing.
+func (limiter *Limiter) sweep(now time.Time) {
+	if now.Sub(limiter.lastSweep) < sweepInterval {
+		return
+	}
+
+	for key, current := range limiter.buckets {
+		if now.Sub(current.updated) >= sweepInterval {
+			delete(limiter.buckets, key)
+		}
+	}
+	limiter.lastSweep = now
+}
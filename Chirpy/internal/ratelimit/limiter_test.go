@@ -0,0 +1,90 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+
+func newTestLimiter(now *time.Time) *Limiter {
+	limiter := NewLimiter()
+	limiter.now = func() time.Time { return *now }
+	limiter.lastSweep = *now
+	return limiter
+}
+
+
+func TestAllowBurstThenRefill(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	rate := Rate{Requests: 3, Per: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.Allow("user", rate); !ok {
+			t.Fatalf("request %d should be allowed", i+1)
+		}
+	}
+
+	ok, wait := limiter.Allow("user", rate)
+	if ok {
+		t.Fatalf("fourth request should be limited")
+	}
+	if wait != 20*time.Second {
+		t.Errorf("got wait %v, want 20s", wait)
+	}
+
+	now = now.Add(20 * time.Second)
+	if ok, _ := limiter.Allow("user", rate); !ok {
+		t.Errorf("request should be allowed once a token has refilled")
+	}
+}
+
+
+func TestAllowKeysAreIndependent(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	rate := Rate{Requests: 1, Per: time.Minute}
+
+	if ok, _ := limiter.Allow("alice", rate); !ok {
+		t.Fatalf("alice should be allowed")
+	}
+	if ok, _ := limiter.Allow("bob", rate); !ok {
+		t.Errorf("bob should not be limited by alice's requests")
+	}
+}
+
+
+func TestAllowRateChange(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	slow := Rate{Requests: 1, Per: time.Minute}
+	fast := Rate{Requests: 60, Per: time.Minute}
+
+	limiter.Allow("user", slow)
+	if ok, _ := limiter.Allow("user", slow); ok {
+		t.Fatalf("second request at the slow rate should be limited")
+	}
+
+	now = now.Add(time.Second)
+	if ok, _ := limiter.Allow("user", fast); !ok {
+		t.Errorf("a faster rate should refill the bucket sooner")
+	}
+}
+
+
+func TestSweepDropsIdleBuckets(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newTestLimiter(&now)
+	rate := Rate{Requests: 1, Per: time.Minute}
+
+	limiter.Allow("idle", rate)
+	now = now.Add(sweepInterval)
+	limiter.Allow("active", rate)
+
+	if _, ok := limiter.buckets["idle"]; ok {
+		t.Errorf("idle bucket should have been swept")
+	}
+	if _, ok := limiter.buckets["active"]; !ok {
+		t.Errorf("active bucket should be kept")
+	}
+}
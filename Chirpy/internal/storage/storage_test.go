@@ -0,0 +1,57 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+
+func TestLocalStorage(t *testing.T) {
+	ctx := context.Background()
+	local, err := NewLocalStorage(t.TempDir(), "/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := local.Put(ctx, "blob.png", strings.NewReader("contents")); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reader, err := local.Open(ctx, "blob.png")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	if string(data) != "contents" {
+		t.Errorf("got %q, want %q", data, "contents")
+	}
+
+	if url := local.URL("blob.png"); url != "/media/blob.png" {
+		t.Errorf("got url %q", url)
+	}
+
+	if err := local.Delete(ctx, "blob.png"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := local.Open(ctx, "blob.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("got %v, want ErrNotFound", err)
+	}
+}
+
+
+func TestLocalStorageRejectsUnsafeKeys(t *testing.T) {
+	local, err := NewLocalStorage(t.TempDir(), "/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []string{"", "../secret", "nested/blob.png", ".hidden"} {
+		if err := local.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("expected key %q to be rejected", key)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package stream
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Event is a single change pushed to subscribers.
+type Event struct {
+	ID 			uint64
+	Type 		string
+	AuthorID 	uuid.UUID
+	Data 		[]byte
+}
+
+// Filter decides whether a subscriber wants an event.
+type Filter func(Event) bool
+
+type subscriber struct {
+	events 	chan Event
+	filter 	Filter
+}
+
+// Hub fans published events out to every interested subscriber and keeps a
+// short history so reconnecting clients can resume where they left off.
+type Hub struct {
+	mu 			sync.Mutex
+	nextID 		uint64
+	history 	[]Event
+	historySize int
+	subscribers map[*subscriber]struct{}
+	bufferSize 	int
+}
+
+
+// NewHub keeps the last historySize events for replay and gives every
+// subscriber a buffer of bufferSize events.
+func NewHub(historySize, bufferSize int) *Hub {
+	return &Hub{
+		nextID: 1,
+		historySize: historySize,
+		subscribers: make(map[*subscriber]struct{}),
+		bufferSize: bufferSize,
+	}
+}
+
+
+// Publish assigns the event the next ID and delivers it. Subscribers that
+// can't keep up are disconnected instead of blocking the publisher; they
+// are expected to reconnect and resume from their last event ID.
+func (hub *Hub) Publish(eventType string, authorID uuid.UUID, data []byte) Event {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	event := Event{
+		ID: hub.nextID,
+		Type: eventType,
+		AuthorID: authorID,
+		Data: data,
+	}
+	hub.nextID++
+
+	hub.history = append(hub.history, event)
+	if len(hub.history) > hub.historySize {
+		hub.history = hub.history[len(hub.history)-hub.historySize:]
+	}
+
+	for sub := range hub.subscribers {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(hub.subscribers, sub)
+			close(sub.events)
+		}
+	}
+
+	return event
+}
+
+
+// Subscribe registers a new subscriber. Any retained events after
+// lastEventID that pass the filter are returned for replay; pass 0 to skip
+// replay. The channel is closed when the subscriber is dropped or cancel
+// is called.
+func (hub *Hub) Subscribe(filter Filter, lastEventID uint64) (<-chan Event, []Event, func()) {
+	hub.mu.Lock()
+	defer hub.mu.Unlock()
+
+	replay := []Event{}
+	if lastEventID > 0 {
+		for _, event := range hub.history {
+			if event.ID > lastEventID && (filter == nil || filter(event)) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	sub := &subscriber{
+		events: make(chan Event, hub.bufferSize),
+		filter: filter,
+	}
+	hub.subscribers[sub] = struct{}{}
+
+	cancel := func() {
+		hub.mu.Lock()
+		defer hub.mu.Unlock()
+		if _, ok := hub.subscribers[sub]; ok {
+			delete(hub.subscribers, sub)
+			close(sub.events)
+		}
+	}
+
+	return sub.events, replay, cancel
+}
@@ -0,0 +1,67 @@
+package stream
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+
+func TestPublishSubscribe(t *testing.T) {
+	hub := NewHub(10, 10)
+	author := uuid.New()
+
+	events, replay, cancel := hub.Subscribe(func(event Event) bool {
+		return event.AuthorID == author
+	}, 0)
+	defer cancel()
+
+	if len(replay) != 0 {
+		t.Errorf("got %d replayed events, want 0", len(replay))
+	}
+
+	hub.Publish("chirp.created", uuid.New(), []byte("other"))
+	hub.Publish("chirp.created", author, []byte("mine"))
+
+	event := <-events
+	if string(event.Data) != "mine" || event.ID != 2 {
+		t.Errorf("got event %+v", event)
+	}
+}
+
+
+func TestResumeFromLastEventID(t *testing.T) {
+	hub := NewHub(3, 10)
+	for i := 0; i < 5; i++ {
+		hub.Publish("chirp.created", uuid.New(), nil)
+	}
+
+	_, replay, cancel := hub.Subscribe(nil, 3)
+	defer cancel()
+
+	if len(replay) != 2 || replay[0].ID != 4 || replay[1].ID != 5 {
+		t.Errorf("got replay %+v, want events 4 and 5", replay)
+	}
+
+	// older events than the history keeps are simply gone
+	_, replay, cancel2 := hub.Subscribe(nil, 1)
+	defer cancel2()
+	if len(replay) != 3 || replay[0].ID != 3 {
+		t.Errorf("got replay %+v, want events 3 to 5", replay)
+	}
+}
+
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	hub := NewHub(10, 1)
+	events, _, cancel := hub.Subscribe(nil, 0)
+	defer cancel()
+
+	hub.Publish("chirp.created", uuid.New(), nil)
+	hub.Publish("chirp.created", uuid.New(), nil)
+
+	<-events
+	if _, open := <-events; open {
+		t.Errorf("expected channel of slow subscriber to be closed")
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// chirpReaction is one of the idempotent per-user toggles on a chirp.
+// Counters on the chirps table are maintained by database triggers.
+type chirpReaction struct {
+	name 	string
+	add 	func(context.Context, *database.Queries, uuid.UUID, uuid.UUID) error
+	remove 	func(context.Context, *database.Queries, uuid.UUID, uuid.UUID) error
+}
+
+var likeReaction = chirpReaction{
+	name: "like",
+	add: func(ctx context.Context, queries *database.Queries, userID, chirpID uuid.UUID) error {
+		return queries.LikeChirp(ctx, database.LikeChirpParams{UserID: userID, ChirpID: chirpID})
+	},
+	remove: func(ctx context.Context, queries *database.Queries, userID, chirpID uuid.UUID) error {
+		return queries.UnlikeChirp(ctx, database.UnlikeChirpParams{UserID: userID, ChirpID: chirpID})
+	},
+}
+
+var rechirpReaction = chirpReaction{
+	name: "rechirp",
+	add: func(ctx context.Context, queries *database.Queries, userID, chirpID uuid.UUID) error {
+		return queries.Rechirp(ctx, database.RechirpParams{UserID: userID, ChirpID: chirpID})
+	},
+	remove: func(ctx context.Context, queries *database.Queries, userID, chirpID uuid.UUID) error {
+		return queries.UndoRechirp(ctx, database.UndoRechirpParams{UserID: userID, ChirpID: chirpID})
+	},
+}
+
+
+func (cfg *apiConfig) likeChirp(writer http.ResponseWriter, request *http.Request) {
+	cfg.setChirpReaction(writer, request, likeReaction, true)
+}
+
+
+func (cfg *apiConfig) unlikeChirp(writer http.ResponseWriter, request *http.Request) {
+	cfg.setChirpReaction(writer, request, likeReaction, false)
+}
+
+
+func (cfg *apiConfig) rechirpChirp(writer http.ResponseWriter, request *http.Request) {
+	cfg.setChirpReaction(writer, request, rechirpReaction, true)
+}
+
+
+func (cfg *apiConfig) undoRechirp(writer http.ResponseWriter, request *http.Request) {
+	cfg.setChirpReaction(writer, request, rechirpReaction, false)
+}
+
+
+// setChirpReaction adds or removes the authenticated user's reaction.
+// Both directions are idempotent and answer with 204.
+func (cfg *apiConfig) setChirpReaction(writer http.ResponseWriter, request *http.Request, reaction chirpReaction, enabled bool) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	if enabled {
+		chirp, err := cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+			ID: chirpID,
+			ViewerID: userID,
+		})
+		if err != nil {
+			if err == sql.ErrNoRows {
+				responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+				return
+			}
+			responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+			return
+		}
+		if !chirp.Published {
+			responseError(writer, http.StatusBadRequest, "Chirp has not been published yet", nil)
+			return
+		}
+		err = reaction.add(request.Context(), cfg.dbQueries, userID, chirpID)
+	} else {
+		err = reaction.remove(request.Context(), cfg.dbQueries, userID, chirpID)
+	}
+
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error updating %s", reaction.name), err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -1,23 +1,38 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 	"os"
 	"sync/atomic"
 
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
 	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/mailer"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/moderation"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/ratelimit"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/storage"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/stream"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 type apiConfig struct {
 	fileserverHits 	atomic.Int32
+	db 				*sql.DB
 	dbQueries 		*database.Queries
 	platform		string
-	tokenSecret 	string
+	tokenKeys 		*auth.KeySet
 	polkaKey		string
+	adminKey		string
+	moderation		*moderation.Filter
+	moderationSource	moderation.Source
+	storage			storage.Storage
+	hub				*stream.Hub
+	limiter			*ratelimit.Limiter
+	mailer			mailer.Mailer
 }
 
 
@@ -43,11 +58,15 @@ func main() {
 		log.Fatal("Could not get playform variable from the environment")
 	}
 
+	// access tokens are signed with a key from JWT_KEYS_DIR when one is
+	// configured; the secret then only verifies tokens issued before that
 	secretString := os.Getenv("TOKEN_SECRET_STRING")
-	if secretString == "" {
+	keysDir := os.Getenv("JWT_KEYS_DIR")
+	if secretString == "" && keysDir == "" {
 		log.Fatal("Could not get token generation secret string from the environment")
 	}
 
+	// shared secret Polka signs its webhooks with
 	polkaKey := os.Getenv("POLKA_KEY")
 	if polkaKey == "" {
 		log.Fatal("Could not get polka key from the environment")
@@ -57,10 +76,71 @@ func main() {
 	var server http.Server
 	var cfg apiConfig
 
+	cfg.db = db
 	cfg.dbQueries = database.New(db)
 	cfg.platform = currentPlatform
-	cfg.tokenSecret = secretString
+	if keysDir != "" {
+		cfg.tokenKeys, err = auth.LoadKeySet(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"), secretString)
+		if err != nil {
+			log.Fatalf("Could not load JWT keys: %v", err)
+		}
+	} else {
+		cfg.tokenKeys = auth.NewKeySet(secretString)
+	}
 	cfg.polkaKey = polkaKey
+	// admin endpoints stay disabled unless a key is configured
+	cfg.adminKey = os.Getenv("ADMIN_KEY")
+
+	// banned words come from the database unless a word list file is given
+	if profanityFile := os.Getenv("PROFANITY_FILE"); profanityFile != "" {
+		cfg.moderationSource = moderation.FileSource{Path: profanityFile}
+	} else {
+		cfg.moderationSource = bannedWordSource{queries: cfg.dbQueries}
+	}
+
+	cfg.moderation, err = moderation.NewFilter(nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = cfg.reloadModerationRules(context.Background()); err != nil {
+		log.Fatalf("Could not load banned words: %v", err)
+	}
+
+	// uploaded media lives on local disk unless configured otherwise
+	mediaDir := os.Getenv("MEDIA_DIR")
+	if mediaDir == "" {
+		mediaDir = "media"
+	}
+	cfg.storage, err = storage.NewLocalStorage(mediaDir, "/media")
+	if err != nil {
+		log.Fatalf("Could not set up media storage: %v", err)
+	}
+
+	// mail goes out over SMTP when a server is configured, otherwise it is
+	// written to MAIL_DIR or just logged for local development
+	mailFrom := os.Getenv("MAIL_FROM")
+	if mailFrom == "" {
+		mailFrom = "Chirpy <no-reply@localhost>"
+	}
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		cfg.mailer = mailer.NewSMTPMailer(smtpAddr, mailFrom, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"))
+	} else if mailDir := os.Getenv("MAIL_DIR"); mailDir != "" {
+		cfg.mailer, err = mailer.NewFileMailer(mailDir, mailFrom)
+		if err != nil {
+			log.Fatalf("Could not set up mail directory: %v", err)
+		}
+	} else {
+		cfg.mailer = mailer.LogMailer{From: mailFrom}
+	}
+
+	cfg.hub = stream.NewHub(streamHistorySize, streamBufferSize)
+	cfg.limiter = ratelimit.NewLimiter()
+
+	go runPeriodically(context.Background(), "publishing scheduled chirps", scheduledPublishInterval, cfg.publishDueChirps)
+	go runPeriodically(context.Background(), "expiring subscriptions", subscriptionExpiryInterval, cfg.expireLapsedSubscriptions)
+	go runPeriodically(context.Background(), "deleting accounts", accountPurgeInterval, cfg.purgeDeletedAccounts)
+	go runPeriodically(context.Background(), "pruning refresh tokens", refreshTokenPruneInterval, cfg.pruneRefreshTokens)
+	go runPeriodically(context.Background(), "pruning authorization codes", oauthCodePruneInterval, cfg.pruneOAuthCodes)
 
 	server.Addr = ":8080"
 	server.Handler = serveMux
@@ -71,27 +151,95 @@ func main() {
 			http.StripPrefix(
 				"/app",http.FileServer(http.Dir(".")))))
 
+	serveMux.HandleFunc("GET /media/{key}", cfg.serveMedia)
+
 	// API Routes
 	serveMux.HandleFunc("GET /api/healthz", readinessCheck)
+	serveMux.HandleFunc("GET /.well-known/jwks.json", cfg.getJWKS)
 
 	// API User Routes
 	serveMux.HandleFunc("POST /api/users", cfg.createUser)
-	serveMux.HandleFunc("PUT /api/users", cfg.updateUser)
+	serveMux.HandleFunc("PATCH /api/users/me", cfg.patchUser)
+	serveMux.HandleFunc("DELETE /api/users/me", cfg.deleteMe)
+	serveMux.HandleFunc("GET /api/users/me/export", cfg.exportMe)
+	serveMux.HandleFunc("POST /api/email-verification", cfg.requestEmailVerification)
+	serveMux.HandleFunc("POST /api/email-verification/confirm", cfg.confirmEmailVerification)
+	serveMux.HandleFunc("POST /api/password-reset", cfg.requestPasswordReset)
+	serveMux.HandleFunc("POST /api/password-reset/confirm", cfg.confirmPasswordReset)
 	serveMux.HandleFunc("POST /api/login", cfg.loginUser)
 	serveMux.HandleFunc("POST /api/refresh", cfg.refreshAccessToken)
 	serveMux.HandleFunc("POST /api/revoke", cfg.revokeRefreshToken)
+	serveMux.HandleFunc("GET /api/sessions", cfg.getSessions)
+	serveMux.HandleFunc("DELETE /api/sessions", cfg.deleteAllSessions)
+	serveMux.HandleFunc("DELETE /api/sessions/{sessionID}", cfg.deleteSession)
+	serveMux.HandleFunc("POST /api/api-keys", cfg.createAPIKey)
+	serveMux.HandleFunc("GET /api/api-keys", cfg.getAPIKeys)
+	serveMux.HandleFunc("DELETE /api/api-keys/{keyID}", cfg.deleteAPIKey)
+	serveMux.HandleFunc("POST /api/oauth/clients", cfg.createOAuthClient)
+	serveMux.HandleFunc("GET /api/oauth/clients", cfg.getOAuthClients)
+	serveMux.HandleFunc("DELETE /api/oauth/clients/{clientID}", cfg.deleteOAuthClient)
+	serveMux.HandleFunc("GET /api/oauth/authorize", cfg.getAuthorization)
+	serveMux.HandleFunc("POST /api/oauth/authorize", cfg.approveAuthorization)
+	serveMux.HandleFunc("POST /api/oauth/token", cfg.issueOAuthToken)
+	serveMux.HandleFunc("POST /api/oauth/introspect", cfg.introspectOAuthToken)
+	serveMux.HandleFunc("POST /api/oauth/revoke", cfg.revokeOAuthToken)
+	serveMux.HandleFunc("GET /api/users/me/subscription", cfg.getMySubscription)
+	serveMux.HandleFunc("PUT /api/users/me/avatar", cfg.uploadAvatar)
+	serveMux.HandleFunc("DELETE /api/users/me/avatar", cfg.deleteAvatar)
+	serveMux.HandleFunc("GET /api/users/{handle}", cfg.getProfile)
+
+	// API Follow Routes
+	serveMux.HandleFunc("PUT /api/users/{userID}/follow", cfg.followUser)
+	serveMux.HandleFunc("DELETE /api/users/{userID}/follow", cfg.unfollowUser)
+	serveMux.HandleFunc("GET /api/users/{userID}/followers", cfg.getFollowers)
+	serveMux.HandleFunc("GET /api/users/{userID}/following", cfg.getFollowing)
+	serveMux.HandleFunc("GET /api/timeline", cfg.getTimeline)
 
 	// API Chirp Routes
 	serveMux.HandleFunc("POST /api/chirps", cfg.createChirp)
 	serveMux.HandleFunc("GET /api/chirps", cfg.getAllChirps)
+	serveMux.HandleFunc("GET /api/chirps/search", cfg.searchChirps)
+	serveMux.HandleFunc("GET /api/chirps/scheduled", cfg.getScheduledChirps)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/schedule", cfg.cancelScheduledChirp)
 	serveMux.HandleFunc("GET /api/chirps/{chirpID}", cfg.getChirp)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/thread", cfg.getChirpThread)
+	serveMux.HandleFunc("PUT /api/chirps/{chirpID}", cfg.updateChirp)
 	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}", cfg.deleteChirp)
-
-	serveMux.HandleFunc("POST /api/polka/webhooks", cfg.upgradeUserToChirpyRed)
+	serveMux.HandleFunc("GET /api/chirps/{chirpID}/revisions", cfg.getChirpRevisions)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/attachments", cfg.uploadChirpAttachment)
+	serveMux.HandleFunc("GET /api/stream", cfg.streamChirps)
+	serveMux.HandleFunc("PUT /api/chirps/{chirpID}/like", cfg.likeChirp)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/like", cfg.unlikeChirp)
+	serveMux.HandleFunc("PUT /api/chirps/{chirpID}/rechirp", cfg.rechirpChirp)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/rechirp", cfg.undoRechirp)
+	serveMux.HandleFunc("POST /api/chirps/{chirpID}/vote", cfg.voteInPoll)
+	serveMux.HandleFunc("PUT /api/chirps/{chirpID}/bookmark", cfg.bookmarkChirp)
+	serveMux.HandleFunc("DELETE /api/chirps/{chirpID}/bookmark", cfg.removeBookmark)
+
+	// API Bookmark Routes
+	serveMux.HandleFunc("GET /api/bookmarks", cfg.getBookmarks)
+	serveMux.HandleFunc("POST /api/collections", cfg.createCollection)
+	serveMux.HandleFunc("GET /api/collections", cfg.getCollections)
+	serveMux.HandleFunc("DELETE /api/collections/{collectionID}", cfg.deleteCollection)
+	serveMux.HandleFunc("GET /api/collections/{collectionID}/chirps", cfg.getCollectionChirps)
+	serveMux.HandleFunc("PUT /api/collections/{collectionID}/chirps/{chirpID}", cfg.addChirpToCollection)
+	serveMux.HandleFunc("DELETE /api/collections/{collectionID}/chirps/{chirpID}", cfg.removeChirpFromCollection)
+
+	// API Tag Routes
+	serveMux.HandleFunc("GET /api/tags/trending", cfg.getTrendingTags)
+	serveMux.HandleFunc("GET /api/tags/{tag}/chirps", cfg.getTagChirps)
+
+	serveMux.HandleFunc("POST /api/polka/webhooks", cfg.handlePolkaWebhook)
 
 	// Admin Routes
 	serveMux.HandleFunc("GET /admin/metrics", cfg.returnMetrics)
 	serveMux.HandleFunc("POST /admin/reset", cfg.deleteAllUsers)
+	serveMux.HandleFunc("GET /admin/moderation/words", cfg.listBannedWords)
+	serveMux.HandleFunc("PUT /admin/moderation/words/{word}", cfg.putBannedWord)
+	serveMux.HandleFunc("DELETE /admin/moderation/words/{word}", cfg.deleteBannedWord)
+	serveMux.HandleFunc("POST /admin/moderation/reload", cfg.reloadBannedWords)
+	serveMux.HandleFunc("GET /admin/polka/events", cfg.listPolkaEvents)
+	serveMux.HandleFunc("POST /admin/polka/events/{eventID}/replay", cfg.replayPolkaEvent)
 
 	err = server.ListenAndServe()
 
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/moderation"
+)
+
+// bannedWordSource serves moderation rules from the banned_words table.
+type bannedWordSource struct {
+	queries *database.Queries
+}
+
+
+func (source bannedWordSource) LoadRules(ctx context.Context) ([]moderation.Rule, error) {
+	words, err := source.queries.ListBannedWords(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]moderation.Rule, len(words))
+	for i, word := range words {
+		rules[i] = moderation.Rule{
+			Word: word.Word,
+			Policy: moderation.Policy(word.Policy),
+			Replacement: word.Replacement,
+		}
+	}
+	return rules, nil
+}
+
+
+// cleanUpChirp runs a chirp body through the moderation filter.
+func (cfg *apiConfig) cleanUpChirp(chirp string) (string, error) {
+	return cfg.moderation.Clean(chirp)
+}
+
+
+func (cfg *apiConfig) reloadModerationRules(ctx context.Context) error {
+	rules, err := cfg.moderationSource.LoadRules(ctx)
+	if err != nil {
+		return err
+	}
+	return cfg.moderation.SetRules(rules)
+}
+
+
+// authorizeAdmin checks the request for the ADMIN_KEY api key and writes the
+// error response itself when it is missing or wrong.
+func (cfg *apiConfig) authorizeAdmin(writer http.ResponseWriter, request *http.Request) bool {
+	if cfg.adminKey == "" {
+		responseError(writer, http.StatusForbidden, "Admin API is disabled", nil)
+		return false
+	}
+
+	apiKey, err := auth.GetAPIKey(request.Header)
+	if err != nil {
+		responseError(writer, http.StatusUnauthorized, "Malformed/Missing API key", err)
+		return false
+	}
+
+	if subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.adminKey)) != 1 {
+		responseError(writer, http.StatusUnauthorized, "Invalid API key", nil)
+		return false
+	}
+
+	return true
+}
+
+
+func (cfg *apiConfig) listBannedWords(writer http.ResponseWriter, request *http.Request) {
+	if !cfg.authorizeAdmin(writer, request) {
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, cfg.moderation.Rules())
+}
+
+
+func (cfg *apiConfig) putBannedWord(writer http.ResponseWriter, request *http.Request) {
+	type requestData struct {
+		Policy 		moderation.Policy 	`json:"policy"`
+		Replacement string 				`json:"replacement"`
+	}
+
+	if !cfg.authorizeAdmin(writer, request) {
+		return
+	}
+
+	if _, ok := cfg.moderationSource.(bannedWordSource); !ok {
+		responseError(writer, http.StatusConflict, "Banned words are loaded from a file; edit it and reload instead", nil)
+		return
+	}
+
+	var data requestData
+	decoder := json.NewDecoder(request.Body)
+	if err := decoder.Decode(&data); err != nil {
+		responseError(writer, http.StatusBadRequest, "Error decoding JSON", err)
+		return
+	}
+
+	rule, err := moderation.ValidateRule(moderation.Rule{
+		Word: request.PathValue("word"),
+		Policy: data.Policy,
+		Replacement: data.Replacement,
+	})
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	_, err = cfg.dbQueries.UpsertBannedWord(request.Context(), database.UpsertBannedWordParams{
+		Word: rule.Word,
+		Policy: string(rule.Policy),
+		Replacement: rule.Replacement,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error saving banned word", err)
+		return
+	}
+
+	if err := cfg.reloadModerationRules(request.Context()); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error reloading banned words", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, rule)
+}
+
+
+func (cfg *apiConfig) deleteBannedWord(writer http.ResponseWriter, request *http.Request) {
+	if !cfg.authorizeAdmin(writer, request) {
+		return
+	}
+
+	if _, ok := cfg.moderationSource.(bannedWordSource); !ok {
+		responseError(writer, http.StatusConflict, "Banned words are loaded from a file; edit it and reload instead", nil)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteBannedWord(request.Context(), moderation.Normalize(request.PathValue("word")))
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error deleting banned word", err)
+		return
+	}
+
+	if deleted == 0 {
+		responseError(writer, http.StatusNotFound, "Word is not banned", nil)
+		return
+	}
+
+	if err := cfg.reloadModerationRules(request.Context()); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error reloading banned words", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+// reloadBannedWords re-reads the rules from their source, e.g. after the
+// word list file was edited.
+func (cfg *apiConfig) reloadBannedWords(writer http.ResponseWriter, request *http.Request) {
+	if !cfg.authorizeAdmin(writer, request) {
+		return
+	}
+
+	if err := cfg.reloadModerationRules(request.Context()); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error reloading banned words", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, cfg.moderation.Rules())
+}
@@ -0,0 +1,709 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	authorizationCodeLifetime 	= 10 * time.Minute
+	oauthCodePruneInterval 		= time.Hour
+	maxOAuthClientNameLength 	= 100
+	maxRedirectURIs 			= 10
+)
+
+// what a user can grant an OAuth client. Account access stays with the user,
+// so a client can't change their password or mint itself API keys.
+var oauthScopes = []string{auth.ScopeChirpsRead, auth.ScopeChirpsWrite}
+
+// OAuthClient is an app registered to ask users for access. The secret is
+// only ever shown in the response that registers a confidential client.
+type OAuthClient struct {
+	ID 				uuid.UUID 	`json:"client_id"`
+	Name 			string 		`json:"name"`
+	RedirectURIs 	[]string 	`json:"redirect_uris"`
+	Confidential 	bool 		`json:"confidential"`
+	CreatedAt 		time.Time 	`json:"created_at"`
+	ClientSecret 	string 		`json:"client_secret,omitempty"`
+}
+
+// authorizationRequest is a validated request to the authorization endpoint.
+type authorizationRequest struct {
+	client 			database.OauthClient
+	redirectURI 	string
+	scopes 			[]string
+	state 			string
+	codeChallenge 	string
+}
+
+
+func oauthClientFromDB(client database.OauthClient) OAuthClient {
+	return OAuthClient{
+		ID: client.ID,
+		Name: client.Name,
+		RedirectURIs: client.RedirectUris,
+		Confidential: client.SecretHash != "",
+		CreatedAt: client.CreatedAt,
+	}
+}
+
+
+// responseOAuthError answers in the error format of RFC 6749 section 5.2,
+// which OAuth client libraries expect instead of ours.
+func responseOAuthError(writer http.ResponseWriter, status int, code, description string, err error) {
+	if err != nil {
+		log.Println(err)
+	}
+	responseJSON(writer, status, struct{
+		Error 				string `json:"error"`
+		ErrorDescription 	string `json:"error_description,omitempty"`
+	}{
+		Error: code,
+		ErrorDescription: description,
+	})
+}
+
+
+// validateRedirectURI accepts absolute https URLs, and plain http ones on
+// the loopback interface for apps running on the user's machine.
+func validateRedirectURI(rawURI string) error {
+	redirectURI, err := url.Parse(rawURI)
+	if err != nil || !redirectURI.IsAbs() || redirectURI.Host == "" {
+		return fmt.Errorf("Redirect URI %q is not an absolute URL", rawURI)
+	}
+	if redirectURI.Fragment != "" {
+		return fmt.Errorf("Redirect URI %q cannot have a fragment", rawURI)
+	}
+
+	switch redirectURI.Scheme {
+	case "https":
+		return nil
+	case "http":
+		switch redirectURI.Hostname() {
+		case "localhost", "127.0.0.1", "::1":
+			return nil
+		}
+	}
+	return fmt.Errorf("Redirect URI %q must use https", rawURI)
+}
+
+
+func (cfg *apiConfig) createOAuthClient(writer http.ResponseWriter, request *http.Request) {
+	type createData struct {
+		Name 			string 		`json:"name"`
+		RedirectURIs 	[]string 	`json:"redirect_uris"`
+		Confidential 	bool 		`json:"confidential"`
+	}
+
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := createData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	if requestData.Name == "" {
+		responseError(writer, http.StatusBadRequest, "Name cannot be empty", nil)
+		return
+	}
+	if utf8.RuneCountInString(requestData.Name) > maxOAuthClientNameLength {
+		responseError(writer, http.StatusBadRequest, "Name is too long", nil)
+		return
+	}
+
+	if len(requestData.RedirectURIs) == 0 || len(requestData.RedirectURIs) > maxRedirectURIs {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Clients need 1 to %d redirect URIs", maxRedirectURIs), nil)
+		return
+	}
+	for _, redirectURI := range requestData.RedirectURIs {
+		if err := validateRedirectURI(redirectURI); err != nil {
+			responseError(writer, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+
+	secret, secretHash := "", ""
+	if requestData.Confidential {
+		var err error
+		secret, err = auth.MakeRefreshToken()
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error generating client secret", err)
+			return
+		}
+		secretHash = auth.HashToken(secret)
+	}
+
+	client, err := cfg.dbQueries.CreateOAuthClient(request.Context(), database.CreateOAuthClientParams{
+		OwnerID: userID,
+		Name: requestData.Name,
+		RedirectUris: requestData.RedirectURIs,
+		SecretHash: secretHash,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error registering client", err)
+		return
+	}
+
+	response := oauthClientFromDB(client)
+	response.ClientSecret = secret
+	responseJSON(writer, http.StatusCreated, response)
+}
+
+
+func (cfg *apiConfig) getOAuthClients(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListOAuthClients(request.Context(), userID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching clients", err)
+		return
+	}
+
+	clients := make([]OAuthClient, len(rows))
+	for i, row := range rows {
+		clients[i] = oauthClientFromDB(row)
+	}
+
+	responseJSON(writer, http.StatusOK, clients)
+}
+
+
+// deleteOAuthClient unregisters a client. Its codes and refresh tokens go
+// with it; access tokens already issued stay valid until they expire.
+func (cfg *apiConfig) deleteOAuthClient(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	clientID, err := uuid.Parse(request.PathValue("clientID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	deleted, err := cfg.dbQueries.DeleteOAuthClient(request.Context(), database.DeleteOAuthClientParams{
+		ID: clientID,
+		OwnerID: userID,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error deleting client", err)
+		return
+	}
+	if deleted == 0 {
+		responseError(writer, http.StatusNotFound, "Client does not exist", nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+// redirect builds the URL the user is sent back to the client with.
+func (authRequest authorizationRequest) redirect(params url.Values) string {
+	// registered URIs were checked to parse
+	target, _ := url.Parse(authRequest.redirectURI)
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if authRequest.state != "" {
+		query.Set("state", authRequest.state)
+	}
+	target.RawQuery = query.Encode()
+
+	return target.String()
+}
+
+
+// parseAuthorizationRequest checks the query of an authorization request and
+// writes the error response itself. Until the client and redirect URI are
+// known to match nothing may be sent back to the client, so only errors
+// after that point carry a redirect_to for the front end to follow.
+func (cfg *apiConfig) parseAuthorizationRequest(writer http.ResponseWriter, request *http.Request) (authorizationRequest, bool) {
+	query := request.URL.Query()
+
+	clientID, err := uuid.Parse(query.Get("client_id"))
+	if err != nil {
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_request", "Malformed client_id", err)
+		return authorizationRequest{}, false
+	}
+
+	client, err := cfg.dbQueries.GetOAuthClient(request.Context(), clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseOAuthError(writer, http.StatusBadRequest, "invalid_request", "Unknown client", err)
+			return authorizationRequest{}, false
+		}
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error fetching client", err)
+		return authorizationRequest{}, false
+	}
+
+	authRequest := authorizationRequest{
+		client: client,
+		redirectURI: query.Get("redirect_uri"),
+		state: query.Get("state"),
+	}
+	if !slices.Contains(client.RedirectUris, authRequest.redirectURI) {
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_request", "redirect_uri is not registered for this client", nil)
+		return authorizationRequest{}, false
+	}
+
+	reject := func(code, description string) (authorizationRequest, bool) {
+		responseJSON(writer, http.StatusBadRequest, struct{
+			Error 				string `json:"error"`
+			ErrorDescription 	string `json:"error_description"`
+			RedirectTo 			string `json:"redirect_to"`
+		}{
+			Error: code,
+			ErrorDescription: description,
+			RedirectTo: authRequest.redirect(url.Values{"error": {code}, "error_description": {description}}),
+		})
+		return authorizationRequest{}, false
+	}
+
+	if query.Get("response_type") != "code" {
+		return reject("unsupported_response_type", "response_type must be code")
+	}
+
+	authRequest.scopes = auth.ParseScopes(query.Get("scope"))
+	if len(authRequest.scopes) == 0 {
+		return reject("invalid_scope", "At least one scope is required")
+	}
+	for _, scope := range authRequest.scopes {
+		if !auth.HasScope(oauthScopes, scope) {
+			return reject("invalid_scope", fmt.Sprintf("Scope %q can't be granted to clients", scope))
+		}
+	}
+
+	authRequest.codeChallenge = query.Get("code_challenge")
+	if err := auth.ValidateCodeChallenge(authRequest.codeChallenge, query.Get("code_challenge_method")); err != nil {
+		return reject("invalid_request", err.Error())
+	}
+
+	return authRequest, true
+}
+
+
+// getAuthorization describes an authorization request so the front end can
+// ask the signed in user for consent.
+func (cfg *apiConfig) getAuthorization(writer http.ResponseWriter, request *http.Request) {
+	if _, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount); !ok {
+		return
+	}
+
+	authRequest, ok := cfg.parseAuthorizationRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	type clientSummary struct {
+		ID 		uuid.UUID 	`json:"client_id"`
+		Name 	string 		`json:"name"`
+	}
+
+	responseJSON(writer, http.StatusOK, struct{
+		Client 		clientSummary 	`json:"client"`
+		Scopes 		[]string 		`json:"scopes"`
+		RedirectURI string 			`json:"redirect_uri"`
+	}{
+		Client: clientSummary{ID: authRequest.client.ID, Name: authRequest.client.Name},
+		Scopes: authRequest.scopes,
+		RedirectURI: authRequest.redirectURI,
+	})
+}
+
+
+// approveAuthorization records the user's answer to an authorization request,
+// sent with the same query. Either way the response names the URL to send
+// the user back to the client with, carrying a code if they approved.
+func (cfg *apiConfig) approveAuthorization(writer http.ResponseWriter, request *http.Request) {
+	type consentData struct {
+		Approve bool `json:"approve"`
+	}
+
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	authRequest, ok := cfg.parseAuthorizationRequest(writer, request)
+	if !ok {
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := consentData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	type redirectResponse struct {
+		RedirectTo string `json:"redirect_to"`
+	}
+
+	if !requestData.Approve {
+		responseJSON(writer, http.StatusOK, redirectResponse{
+			RedirectTo: authRequest.redirect(url.Values{"error": {"access_denied"}}),
+		})
+		return
+	}
+
+	code, err := auth.MakeRefreshToken()
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error generating authorization code", err)
+		return
+	}
+
+	err = cfg.dbQueries.CreateOAuthCode(request.Context(), database.CreateOAuthCodeParams{
+		CodeHash: auth.HashToken(code),
+		ClientID: authRequest.client.ID,
+		UserID: userID,
+		RedirectUri: authRequest.redirectURI,
+		Scopes: authRequest.scopes,
+		CodeChallenge: authRequest.codeChallenge,
+		FamilyID: uuid.New(),
+		ExpiresAt: time.Now().UTC().Add(authorizationCodeLifetime),
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error creating authorization code", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, redirectResponse{
+		RedirectTo: authRequest.redirect(url.Values{"code": {code}}),
+	})
+}
+
+
+// authenticateOAuthClient identifies the client calling the token,
+// introspection or revocation endpoint, by HTTP Basic auth or by client_id
+// and client_secret in the form. Public clients only send their ID. The form
+// must already be parsed; the error response is written here.
+func (cfg *apiConfig) authenticateOAuthClient(writer http.ResponseWriter, request *http.Request) (database.OauthClient, bool) {
+	rawClientID, secret, basic := request.BasicAuth()
+	if basic {
+		// RFC 6749 section 2.3.1 form-encodes both before they are joined
+		rawClientID, _ = url.QueryUnescape(rawClientID)
+		secret, _ = url.QueryUnescape(secret)
+	} else {
+		rawClientID = request.PostForm.Get("client_id")
+		secret = request.PostForm.Get("client_secret")
+	}
+
+	reject := func(err error) (database.OauthClient, bool) {
+		if basic {
+			writer.Header().Set("WWW-Authenticate", `Basic realm="chirpy"`)
+		}
+		responseOAuthError(writer, http.StatusUnauthorized, "invalid_client", "Client authentication failed", err)
+		return database.OauthClient{}, false
+	}
+
+	clientID, err := uuid.Parse(rawClientID)
+	if err != nil {
+		return reject(err)
+	}
+
+	client, err := cfg.dbQueries.GetOAuthClient(request.Context(), clientID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return reject(err)
+		}
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error fetching client", err)
+		return database.OauthClient{}, false
+	}
+
+	if client.SecretHash == "" {
+		if secret != "" {
+			return reject(fmt.Errorf("public client %s sent a secret", client.ID))
+		}
+		return client, true
+	}
+
+	if subtle.ConstantTimeCompare([]byte(auth.HashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return reject(fmt.Errorf("wrong secret for client %s", client.ID))
+	}
+	return client, true
+}
+
+
+// issueOAuthToken is the token endpoint. It exchanges authorization codes
+// and rotates refresh tokens issued to clients.
+func (cfg *apiConfig) issueOAuthToken(writer http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_request", "Malformed form body", err)
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(writer, request)
+	if !ok {
+		return
+	}
+
+	switch request.PostForm.Get("grant_type") {
+	case "authorization_code":
+		cfg.exchangeAuthorizationCode(writer, request, client)
+	case "refresh_token":
+		cfg.refreshOAuthToken(writer, request, client)
+	default:
+		responseOAuthError(writer, http.StatusBadRequest, "unsupported_grant_type", "grant_type must be authorization_code or refresh_token", nil)
+	}
+}
+
+
+// exchangeAuthorizationCode trades a code for the client's first tokens. A
+// code is spent by the first attempt to use it, successful or not. If it is
+// presented again it may have been stolen, so the tokens it was exchanged for
+// are revoked.
+func (cfg *apiConfig) exchangeAuthorizationCode(writer http.ResponseWriter, request *http.Request, client database.OauthClient) {
+	form := request.PostForm
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	code, err := queries.GetOAuthCodeForUpdate(request.Context(), auth.HashToken(form.Get("code")))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseOAuthError(writer, http.StatusBadRequest, "invalid_grant", "Invalid authorization code", err)
+			return
+		}
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error fetching authorization code", err)
+		return
+	}
+
+	if code.UsedAt.Valid {
+		if _, err := queries.RevokeRefreshTokenFamily(request.Context(), code.FamilyID); err != nil {
+			responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error revoking refresh tokens", err)
+			return
+		}
+		if err := tx.Commit(); err != nil {
+			responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error revoking refresh tokens", err)
+			return
+		}
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_grant", "Authorization code was already used", nil)
+		return
+	}
+
+	if err := queries.MarkOAuthCodeUsed(request.Context(), code.CodeHash); err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error using authorization code", err)
+		return
+	}
+
+	rejection := ""
+	switch {
+	case code.ClientID != client.ID:
+		rejection = "Authorization code was issued to another client"
+	case code.ExpiresAt.Before(time.Now()):
+		rejection = "Authorization code is expired"
+	case code.RedirectUri != form.Get("redirect_uri"):
+		rejection = "redirect_uri does not match the authorization request"
+	case !auth.VerifyPKCE(form.Get("code_verifier"), code.CodeChallenge):
+		rejection = "Invalid code_verifier"
+	}
+	if rejection != "" {
+		if err := tx.Commit(); err != nil {
+			responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error using authorization code", err)
+			return
+		}
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_grant", rejection, nil)
+		return
+	}
+
+	grant := tokenGrant{
+		userID: code.UserID,
+		familyID: code.FamilyID,
+		clientID: uuid.NullUUID{UUID: client.ID, Valid: true},
+		scopes: code.Scopes,
+	}
+	refreshToken, err := issueRefreshToken(request.Context(), queries, grant, clientInfoFrom(request))
+	if err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error creating refresh token", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error using authorization code", err)
+		return
+	}
+
+	cfg.responseOAuthToken(writer, grant, refreshToken)
+}
+
+
+func (cfg *apiConfig) refreshOAuthToken(writer http.ResponseWriter, request *http.Request, client database.OauthClient) {
+	clientID := uuid.NullUUID{UUID: client.ID, Valid: true}
+	grant, refreshToken, err := cfg.rotateRefreshToken(request.Context(), request.PostForm.Get("refresh_token"), clientID, clientInfoFrom(request))
+	if err != nil {
+		if rejection := refreshTokenRejection(err); rejection != nil {
+			responseOAuthError(writer, http.StatusBadRequest, "invalid_grant", rejection.Error(), err)
+			return
+		}
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error rotating refresh token", err)
+		return
+	}
+
+	cfg.responseOAuthToken(writer, grant, refreshToken)
+}
+
+
+// responseOAuthToken sends an access token for the grant along with its
+// refresh token, as RFC 6749 section 5.1 lays out.
+func (cfg *apiConfig) responseOAuthToken(writer http.ResponseWriter, grant tokenGrant, refreshToken string) {
+	accessToken, err := cfg.tokenKeys.MakeClientJWT(grant.userID, grant.clientID.UUID.String(), grant.scopes, accessTokenLifetime)
+	if err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error generating access token", err)
+		return
+	}
+
+	writer.Header().Set("Cache-Control", "no-store")
+	responseJSON(writer, http.StatusOK, struct{
+		AccessToken 	string `json:"access_token"`
+		TokenType 		string `json:"token_type"`
+		ExpiresIn 		int    `json:"expires_in"`
+		RefreshToken 	string `json:"refresh_token"`
+		Scope 			string `json:"scope"`
+	}{
+		AccessToken: accessToken,
+		TokenType: "Bearer",
+		ExpiresIn: int(accessTokenLifetime.Seconds()),
+		RefreshToken: refreshToken,
+		Scope: strings.Join(grant.scopes, " "),
+	})
+}
+
+
+// introspectOAuthToken tells a client whether a token is live, as in
+// RFC 7662. Clients can only look at their own tokens; anything else is
+// reported inactive.
+func (cfg *apiConfig) introspectOAuthToken(writer http.ResponseWriter, request *http.Request) {
+	type introspection struct {
+		Active 		bool 	`json:"active"`
+		Scope 		string 	`json:"scope,omitempty"`
+		ClientID 	string 	`json:"client_id,omitempty"`
+		Subject 	string 	`json:"sub,omitempty"`
+		ExpiresAt 	int64 	`json:"exp,omitempty"`
+		IssuedAt 	int64 	`json:"iat,omitempty"`
+	}
+
+	if err := request.ParseForm(); err != nil {
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_request", "Malformed form body", err)
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(writer, request)
+	if !ok {
+		return
+	}
+	token := request.PostForm.Get("token")
+
+	if claims, err := cfg.tokenKeys.ParseJWT(token); err == nil {
+		if claims.ClientID != client.ID.String() {
+			responseJSON(writer, http.StatusOK, introspection{})
+			return
+		}
+
+		response := introspection{
+			Active: true,
+			Scope: claims.Scope,
+			ClientID: claims.ClientID,
+			Subject: claims.Subject,
+			ExpiresAt: claims.ExpiresAt.Unix(),
+		}
+		if claims.IssuedAt != nil {
+			response.IssuedAt = claims.IssuedAt.Unix()
+		}
+		responseJSON(writer, http.StatusOK, response)
+		return
+	}
+
+	refreshToken, err := cfg.dbQueries.GetRefreshTokenWithHash(request.Context(), auth.HashToken(token))
+	if err != nil && err != sql.ErrNoRows {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error fetching token", err)
+		return
+	}
+	if err == sql.ErrNoRows || !refreshToken.ClientID.Valid || refreshToken.ClientID.UUID != client.ID ||
+		refreshToken.RevokedAt.Valid || refreshToken.RotatedAt.Valid || refreshToken.ExpiresAt.Before(time.Now()) {
+		responseJSON(writer, http.StatusOK, introspection{})
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, introspection{
+		Active: true,
+		Scope: strings.Join(refreshToken.Scopes, " "),
+		ClientID: client.ID.String(),
+		Subject: refreshToken.UserID.String(),
+		ExpiresAt: refreshToken.ExpiresAt.Unix(),
+		IssuedAt: refreshToken.CreatedAt.Unix(),
+	})
+}
+
+
+// revokeOAuthToken is the RFC 7009 revocation endpoint. Revoking a refresh
+// token ends the whole grant. Access tokens can't be revoked and simply run
+// out within the hour, so like unknown tokens they are acknowledged without
+// effect.
+func (cfg *apiConfig) revokeOAuthToken(writer http.ResponseWriter, request *http.Request) {
+	if err := request.ParseForm(); err != nil {
+		responseOAuthError(writer, http.StatusBadRequest, "invalid_request", "Malformed form body", err)
+		return
+	}
+
+	client, ok := cfg.authenticateOAuthClient(writer, request)
+	if !ok {
+		return
+	}
+
+	refreshToken, err := cfg.dbQueries.GetRefreshTokenWithHash(request.Context(), auth.HashToken(request.PostForm.Get("token")))
+	if err == sql.ErrNoRows || (err == nil && refreshToken.ClientID.UUID != client.ID) {
+		writer.WriteHeader(http.StatusOK)
+		return
+	}
+	if err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error fetching token", err)
+		return
+	}
+
+	if _, err := cfg.dbQueries.RevokeRefreshTokenFamily(request.Context(), refreshToken.FamilyID); err != nil {
+		responseOAuthError(writer, http.StatusInternalServerError, "server_error", "Error revoking token", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusOK)
+}
+
+
+// pruneOAuthCodes drops expired authorization codes. Used ones are kept until
+// then so replays can still be detected.
+func (cfg *apiConfig) pruneOAuthCodes(ctx context.Context) error {
+	_, err := cfg.dbQueries.DeleteExpiredOAuthCodes(ctx)
+	return err
+}
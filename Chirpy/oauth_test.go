@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+)
+
+
+func TestValidateRedirectURI(t *testing.T) {
+	tests := []struct {
+		uri 	string
+		valid 	bool
+	}{
+		{uri: "https://app.example.com/callback", valid: true},
+		{uri: "http://localhost:8080/callback", valid: true},
+		{uri: "http://127.0.0.1/callback", valid: true},
+		{uri: "http://[::1]:9000/cb", valid: true},
+		{uri: "http://app.example.com/callback", valid: false},
+		{uri: "https://app.example.com/callback#frag", valid: false},
+		{uri: "/callback", valid: false},
+		{uri: "javascript:alert(1)", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.uri, func(t *testing.T) {
+			err := validateRedirectURI(test.uri)
+			if (err == nil) != test.valid {
+				t.Errorf("got error %v, want valid=%v", err, test.valid)
+			}
+		})
+	}
+}
+
+
+func TestAuthorizationRequestRedirect(t *testing.T) {
+	authRequest := authorizationRequest{
+		redirectURI: "https://app.example.com/callback?tenant=1",
+		state: "xyz",
+	}
+
+	got, err := url.Parse(authRequest.redirect(url.Values{"code": {"abc"}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	query := got.Query()
+	if query.Get("code") != "abc" || query.Get("state") != "xyz" || query.Get("tenant") != "1" {
+		t.Errorf("got query %v, want code, state and the registered tenant", query)
+	}
+	if got.Host != "app.example.com" || got.Path != "/callback" {
+		t.Errorf("got %v, want the registered URI", got)
+	}
+}
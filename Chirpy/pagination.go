@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 20
+	maxPageLimit     = 100
+)
+
+// pageCursor marks a position in a (created_at, id) ordered listing.
+type pageCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+type pageRequest struct {
+	Limit      int32
+	Descending bool
+	// Backward is set when the client asked for the page before the cursor
+	// rather than the one after it.
+	Backward bool
+	Cursor   *pageCursor
+}
+
+
+// encodeCursor turns a row position into an opaque string for clients.
+// Postgres timestamps only carry microseconds, so that is all we keep.
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%d:%s", createdAt.UnixMicro(), id.String())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+
+func decodeCursor(cursor string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("cursor is not valid base64: %w", err)
+	}
+
+	micros, idString, found := strings.Cut(string(raw), ":")
+	if !found {
+		return pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+
+	unixMicro, err := strconv.ParseInt(micros, 10, 64)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor timestamp: %w", err)
+	}
+
+	id, err := uuid.Parse(idString)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor id: %w", err)
+	}
+
+	return pageCursor{
+		CreatedAt: time.UnixMicro(unixMicro).UTC(),
+		ID:        id,
+	}, nil
+}
+
+
+// parsePageRequest reads the limit, sort, after and before query parameters
+// shared by every paginated listing.
+func parsePageRequest(query url.Values) (pageRequest, error) {
+	page := pageRequest{Limit: defaultPageLimit}
+
+	if limitString := query.Get("limit"); limitString != "" {
+		limit, err := strconv.Atoi(limitString)
+		if err != nil || limit <= 0 {
+			return pageRequest{}, fmt.Errorf("limit must be a positive integer")
+		}
+		if limit > maxPageLimit {
+			limit = maxPageLimit
+		}
+		page.Limit = int32(limit)
+	}
+
+	switch query.Get("sort") {
+	case "", "asc":
+		page.Descending = false
+	case "desc":
+		page.Descending = true
+	default:
+		return pageRequest{}, fmt.Errorf("sort must be either asc or desc")
+	}
+
+	after := query.Get("after")
+	before := query.Get("before")
+	if after != "" && before != "" {
+		return pageRequest{}, fmt.Errorf("only one of after or before may be given")
+	}
+
+	cursorString := after
+	if before != "" {
+		cursorString = before
+		page.Backward = true
+	}
+
+	if cursorString != "" {
+		cursor, err := decodeCursor(cursorString)
+		if err != nil {
+			return pageRequest{}, err
+		}
+		page.Cursor = &cursor
+	}
+
+	return page, nil
+}
+
+
+// cursorParams splits the cursor into the nullable query arguments the
+// List*Asc/List*Desc queries take.
+func (page pageRequest) cursorParams() (sql.NullTime, uuid.NullUUID) {
+	if page.Cursor == nil {
+		return sql.NullTime{}, uuid.NullUUID{}
+	}
+	return sql.NullTime{Time: page.Cursor.CreatedAt, Valid: true},
+		uuid.NullUUID{UUID: page.Cursor.ID, Valid: true}
+}
+
+
+// scanDescending reports which direction the rows have to be read from the
+// database in. Going backward flips the requested sort order, and the page
+// is reversed again before it is returned.
+func (page pageRequest) scanDescending() bool {
+	return page.Descending != page.Backward
+}
+
+
+// pageResponse is the envelope returned by paginated endpoints.
+type pageResponse[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+
+// buildPage trims the extra look-ahead row fetched by the caller, restores
+// the requested order for backward pages, and fills in the cursors.
+// Callers always ask the database for page.Limit+1 rows.
+func buildPage[T any](page pageRequest, rows []T, position func(T) (time.Time, uuid.UUID)) pageResponse[T] {
+	hasMore := len(rows) > int(page.Limit)
+	if hasMore {
+		rows = rows[:page.Limit]
+	}
+
+	if page.Backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	response := pageResponse[T]{Items: rows}
+	if response.Items == nil {
+		response.Items = []T{}
+	}
+	if len(rows) == 0 {
+		return response
+	}
+
+	first, last := rows[0], rows[len(rows)-1]
+	// there is a following page if we either saw one more row going forward,
+	// or came here by stepping backward from one
+	if (!page.Backward && hasMore) || (page.Backward && page.Cursor != nil) {
+		response.NextCursor = encodeCursor(position(last))
+	}
+	if (page.Backward && hasMore) || (!page.Backward && page.Cursor != nil) {
+		response.PrevCursor = encodeCursor(position(first))
+	}
+
+	return response
+}
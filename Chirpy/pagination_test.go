@@ -0,0 +1,163 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+
+func TestCursorRoundTrip(t *testing.T) {
+	createdAt := time.Date(2025, 3, 14, 15, 9, 26, 535897000, time.UTC)
+	id := uuid.New()
+
+	decoded, err := decodeCursor(encodeCursor(createdAt, id))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !decoded.CreatedAt.Equal(createdAt) {
+		t.Errorf("got created_at %v, want %v", decoded.CreatedAt, createdAt)
+	}
+	if decoded.ID != id {
+		t.Errorf("got id %v, want %v", decoded.ID, id)
+	}
+}
+
+
+func TestParsePageRequest(t *testing.T) {
+	validCursor := encodeCursor(time.Now(), uuid.New())
+
+	tests := []struct {
+		name           string
+		query          string
+		wantErr        bool
+		wantLimit      int32
+		wantDescending bool
+		wantBackward   bool
+		wantCursor     bool
+	}{
+		{
+			name:      "defaults",
+			query:     "",
+			wantLimit: defaultPageLimit,
+		},
+		{
+			name:           "descending with limit",
+			query:          "sort=desc&limit=5",
+			wantLimit:      5,
+			wantDescending: true,
+		},
+		{
+			name:      "limit is capped",
+			query:     "limit=100000",
+			wantLimit: maxPageLimit,
+		},
+		{
+			name:       "after cursor",
+			query:      "after=" + validCursor,
+			wantLimit:  defaultPageLimit,
+			wantCursor: true,
+		},
+		{
+			name:         "before cursor",
+			query:        "before=" + validCursor,
+			wantLimit:    defaultPageLimit,
+			wantBackward: true,
+			wantCursor:   true,
+		},
+		{
+			name:    "both cursors",
+			query:   "after=" + validCursor + "&before=" + validCursor,
+			wantErr: true,
+		},
+		{
+			name:    "garbage cursor",
+			query:   "after=not-a-cursor",
+			wantErr: true,
+		},
+		{
+			name:    "negative limit",
+			query:   "limit=-1",
+			wantErr: true,
+		},
+		{
+			name:    "unknown sort",
+			query:   "sort=sideways",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, _ := url.ParseQuery(tt.query)
+			page, err := parsePageRequest(query)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if page.Limit != tt.wantLimit {
+				t.Errorf("got limit %d, want %d", page.Limit, tt.wantLimit)
+			}
+			if page.Descending != tt.wantDescending {
+				t.Errorf("got descending %v, want %v", page.Descending, tt.wantDescending)
+			}
+			if page.Backward != tt.wantBackward {
+				t.Errorf("got backward %v, want %v", page.Backward, tt.wantBackward)
+			}
+			if (page.Cursor != nil) != tt.wantCursor {
+				t.Errorf("got cursor %v, want cursor %v", page.Cursor, tt.wantCursor)
+			}
+		})
+	}
+}
+
+
+func TestBuildPage(t *testing.T) {
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	rows := make([]Chirp, 4)
+	for i := range rows {
+		rows[i] = Chirp{ID: uuid.New(), CreatedAt: base.Add(time.Duration(i) * time.Minute)}
+	}
+
+	t.Run("first page with more rows", func(t *testing.T) {
+		page := pageRequest{Limit: 3}
+		result := buildPage(page, append([]Chirp{}, rows...), chirpPosition)
+		if len(result.Items) != 3 {
+			t.Fatalf("got %d items, want 3", len(result.Items))
+		}
+		if result.NextCursor != encodeCursor(chirpPosition(rows[2])) {
+			t.Errorf("next cursor does not point at the last returned row")
+		}
+		if result.PrevCursor != "" {
+			t.Errorf("first page should not have a previous cursor")
+		}
+	})
+
+	t.Run("backward page is returned in requested order", func(t *testing.T) {
+		cursor := pageCursor{CreatedAt: base.Add(time.Hour), ID: uuid.New()}
+		page := pageRequest{Limit: 2, Backward: true, Cursor: &cursor}
+		// rows come back from the database in the opposite order
+		scanned := []Chirp{rows[3], rows[2], rows[1]}
+		result := buildPage(page, scanned, chirpPosition)
+		if len(result.Items) != 2 {
+			t.Fatalf("got %d items, want 2", len(result.Items))
+		}
+		if result.Items[0].ID != rows[2].ID || result.Items[1].ID != rows[3].ID {
+			t.Errorf("backward page was not reversed")
+		}
+		if result.PrevCursor == "" || result.NextCursor == "" {
+			t.Errorf("expected both cursors, got prev %q next %q", result.PrevCursor, result.NextCursor)
+		}
+	})
+
+	t.Run("empty page", func(t *testing.T) {
+		result := buildPage(pageRequest{Limit: 2}, nil, chirpPosition)
+		if result.Items == nil || len(result.Items) != 0 {
+			t.Errorf("expected an empty, non-nil item list")
+		}
+	})
+}
@@ -0,0 +1,329 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	polkaSignatureHeader 	= "Polka-Signature"
+	polkaSignatureTolerance = 5 * time.Minute
+	maxWebhookBodySize 		= 1 << 20
+)
+
+// states a received Polka event can be in
+const (
+	polkaEventPending 	= "pending"
+	polkaEventProcessed = "processed"
+	polkaEventIgnored 	= "ignored"
+	polkaEventFailed 	= "failed"
+)
+
+type PolkaEvent struct {
+	ID 			uuid.UUID 		`json:"id"`
+	WebhookID 	string 			`json:"webhook_id"`
+	Event 		string 			`json:"event"`
+	Payload 	json.RawMessage `json:"payload"`
+	Status 		string 			`json:"status"`
+	LastError 	string 			`json:"last_error,omitempty"`
+	Attempts 	int32 			`json:"attempts"`
+	ReceivedAt 	time.Time 		`json:"received_at"`
+	ProcessedAt *time.Time 		`json:"processed_at"`
+}
+
+type polkaWebhook struct {
+	ID 		string `json:"id"`
+	Event 	string `json:"event"`
+	Data 	struct {
+		UserID 		string 		`json:"user_id"`
+		// end of the paid period, for upgrades and renewals
+		ExpiresAt 	*time.Time 	`json:"expires_at"`
+	} `json:"data"`
+}
+
+// polkaRejection is an event that can't be applied because of what it
+// contains. It is recorded as failed instead of being rolled back.
+type polkaRejection struct {
+	code 	int
+	message string
+}
+
+
+func (rejection *polkaRejection) Error() string {
+	return rejection.message
+}
+
+
+func polkaEventFromDB(event database.PolkaEvent) PolkaEvent {
+	converted := PolkaEvent{
+		ID: event.ID,
+		WebhookID: event.WebhookID,
+		Event: event.Event,
+		Payload: event.Payload,
+		Status: event.Status,
+		LastError: event.LastError,
+		Attempts: event.Attempts,
+		ReceivedAt: event.ReceivedAt,
+	}
+	if event.ProcessedAt.Valid {
+		processedAt := event.ProcessedAt.Time
+		converted.ProcessedAt = &processedAt
+	}
+	return converted
+}
+
+
+func polkaEventPosition(event PolkaEvent) (time.Time, uuid.UUID) {
+	return event.ReceivedAt, event.ID
+}
+
+
+// applyPolkaEvent carries out a webhook and returns the status it ends in.
+// Event types we don't know about are kept for inspection but not acted on.
+func applyPolkaEvent(ctx context.Context, queries *database.Queries, webhook polkaWebhook) (string, error) {
+	switch webhook.Event {
+	case "user.upgraded", "user.renewed", "user.canceled", "user.downgraded":
+	default:
+		return polkaEventIgnored, nil
+	}
+
+	userID, err := uuid.Parse(webhook.Data.UserID)
+	if err != nil {
+		return "", &polkaRejection{code: http.StatusBadRequest, message: "Malformed UUID"}
+	}
+
+	switch webhook.Event {
+	case "user.upgraded", "user.renewed":
+		periodEnd := time.Now().UTC().Add(defaultSubscriptionPeriod)
+		if webhook.Data.ExpiresAt != nil {
+			periodEnd = webhook.Data.ExpiresAt.UTC()
+		}
+		err = startSubscription(ctx, queries, userID, periodEnd)
+	case "user.canceled":
+		err = cancelSubscription(ctx, queries, userID)
+	case "user.downgraded":
+		err = endSubscription(ctx, queries, userID)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", &polkaRejection{code: http.StatusNotFound, message: "User does not exist"}
+		}
+		return "", err
+	}
+
+	return polkaEventProcessed, nil
+}
+
+
+// finishPolkaEvent applies an event and records the outcome on its row. A
+// rejection comes back alongside the updated row so callers can report it.
+func finishPolkaEvent(ctx context.Context, queries *database.Queries, event database.PolkaEvent) (database.PolkaEvent, *polkaRejection, error) {
+	var webhook polkaWebhook
+	if err := json.Unmarshal(event.Payload, &webhook); err != nil {
+		return database.PolkaEvent{}, nil, err
+	}
+
+	status, err := applyPolkaEvent(ctx, queries, webhook)
+	lastError := ""
+	var rejection *polkaRejection
+	if errors.As(err, &rejection) {
+		status = polkaEventFailed
+		lastError = rejection.message
+	} else if err != nil {
+		return database.PolkaEvent{}, nil, err
+	}
+
+	finished, err := queries.FinishPolkaEvent(ctx, database.FinishPolkaEventParams{
+		ID: event.ID,
+		Status: status,
+		LastError: lastError,
+	})
+	if err != nil {
+		return database.PolkaEvent{}, nil, err
+	}
+
+	return finished, rejection, nil
+}
+
+
+// handlePolkaWebhook verifies Polka's signature, records the event and acts
+// on it. Deliveries are keyed by their webhook id, so a retried or replayed
+// request is only acted on once; failed ones are tried again.
+func (cfg *apiConfig) handlePolkaWebhook(writer http.ResponseWriter, request *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxWebhookBodySize))
+	if err != nil {
+		responseError(writer, http.StatusRequestEntityTooLarge, "Webhook body is too large", err)
+		return
+	}
+
+	signature := request.Header.Get(polkaSignatureHeader)
+	err = auth.ValidateWebhookSignature(signature, cfg.polkaKey, body, time.Now(), polkaSignatureTolerance)
+	if err != nil {
+		responseError(writer, http.StatusUnauthorized, "Invalid webhook signature", err)
+		return
+	}
+
+	var webhook polkaWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		responseError(writer, http.StatusBadRequest, "Error decoding JSON", err)
+		return
+	}
+	if webhook.ID == "" || webhook.Event == "" {
+		responseError(writer, http.StatusBadRequest, "Webhook id and event are required", nil)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	event, err := queries.RecordPolkaEvent(request.Context(), database.RecordPolkaEventParams{
+		WebhookID: webhook.ID,
+		Event: webhook.Event,
+		Payload: body,
+	})
+	if err == sql.ErrNoRows {
+		// seen this delivery before; the row lock makes concurrent retries
+		// wait for the first one to finish
+		event, err = queries.GetPolkaEventByWebhookIDForUpdate(request.Context(), webhook.ID)
+		if err == nil && event.Status != polkaEventFailed {
+			writer.WriteHeader(http.StatusNoContent)
+			return
+		}
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error recording webhook", err)
+		return
+	}
+
+	_, rejection, err := finishPolkaEvent(request.Context(), queries, event)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error processing webhook", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error processing webhook", err)
+		return
+	}
+
+	if rejection != nil {
+		responseError(writer, rejection.code, rejection.message, rejection)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+func (cfg *apiConfig) listPolkaEvents(writer http.ResponseWriter, request *http.Request) {
+	if !cfg.authorizeAdmin(writer, request) {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var status sql.NullString
+	switch statusString := request.URL.Query().Get("status"); statusString {
+	case "":
+	case polkaEventPending, polkaEventProcessed, polkaEventIgnored, polkaEventFailed:
+		status = sql.NullString{String: statusString, Valid: true}
+	default:
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Unknown event status %q", statusString), nil)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var rows []database.PolkaEvent
+	if page.scanDescending() {
+		rows, err = cfg.dbQueries.ListPolkaEventsDesc(request.Context(), database.ListPolkaEventsDescParams{
+			Status: status,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		rows, err = cfg.dbQueries.ListPolkaEventsAsc(request.Context(), database.ListPolkaEventsAscParams{
+			Status: status,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching webhook events", err)
+		return
+	}
+
+	events := make([]PolkaEvent, len(rows))
+	for i, row := range rows {
+		events[i] = polkaEventFromDB(row)
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, events, polkaEventPosition))
+}
+
+
+// replayPolkaEvent applies a stored event again, whatever state it is in.
+// Renewals without an expires_at are extended from the time of the replay.
+func (cfg *apiConfig) replayPolkaEvent(writer http.ResponseWriter, request *http.Request) {
+	if !cfg.authorizeAdmin(writer, request) {
+		return
+	}
+
+	eventID, err := uuid.Parse(request.PathValue("eventID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	event, err := queries.GetPolkaEventForUpdate(request.Context(), eventID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Webhook event does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching webhook event", err)
+		return
+	}
+
+	finished, _, err := finishPolkaEvent(request.Context(), queries, event)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error replaying webhook event", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error replaying webhook event", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, polkaEventFromDB(finished))
+}
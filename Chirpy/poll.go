@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/moderation"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const (
+	minPollOptions 		= 2
+	maxPollOptions 		= 4
+	maxPollOptionLength = 25
+	minPollDuration 	= 5 * time.Minute
+	maxPollDuration 	= 7 * 24 * time.Hour
+)
+
+type PollOption struct {
+	ID 		uuid.UUID 	`json:"id"`
+	Text 	string 		`json:"text"`
+	// nil while the results are hidden from the viewer
+	Votes 	*int32 		`json:"votes"`
+}
+
+// Poll is attached to a chirp when it is created. Tallies are only shown
+// once the viewer has voted or the poll has closed.
+type Poll struct {
+	ClosesAt 		time.Time 		`json:"closes_at"`
+	Closed 			bool 			`json:"closed"`
+	Options 		[]PollOption 	`json:"options"`
+	TotalVotes 		*int32 			`json:"total_votes"`
+	VotedOptionID 	*uuid.UUID 		`json:"voted_option_id,omitempty"`
+}
+
+// pollData is the poll part of a create chirp request.
+type pollData struct {
+	Options 	[]string 	`json:"options"`
+	ClosesAt 	time.Time 	`json:"closes_at"`
+}
+
+
+// validatePoll checks the shape of a new poll. opensAt is when the chirp
+// goes live, which is later than now for scheduled chirps.
+func validatePoll(poll pollData, opensAt time.Time) error {
+	if len(poll.Options) < minPollOptions || len(poll.Options) > maxPollOptions {
+		return fmt.Errorf("Polls need between %d and %d options", minPollOptions, maxPollOptions)
+	}
+
+	seen := map[string]bool{}
+	for _, option := range poll.Options {
+		if option == "" {
+			return fmt.Errorf("Poll options cannot be empty")
+		}
+		if len(option) > maxPollOptionLength {
+			return fmt.Errorf("Poll option is too long")
+		}
+		if seen[strings.ToLower(option)] {
+			return fmt.Errorf("Poll options must be different from each other")
+		}
+		seen[strings.ToLower(option)] = true
+	}
+
+	duration := poll.ClosesAt.Sub(opensAt)
+	if duration < minPollDuration || duration > maxPollDuration {
+		return fmt.Errorf("Polls must stay open between %v and %v", minPollDuration, maxPollDuration)
+	}
+
+	return nil
+}
+
+
+// preparePoll trims and validates the poll and runs its options through the
+// moderation filter. On failure it writes the error response itself.
+func (cfg *apiConfig) preparePoll(writer http.ResponseWriter, poll pollData, opensAt time.Time) (pollData, bool) {
+	prepared := pollData{ClosesAt: poll.ClosesAt.UTC()}
+	for _, option := range poll.Options {
+		prepared.Options = append(prepared.Options, strings.TrimSpace(option))
+	}
+
+	if err := validatePoll(prepared, opensAt); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return pollData{}, false
+	}
+
+	for i, option := range prepared.Options {
+		cleaned, err := cfg.cleanUpChirp(option)
+		if err != nil {
+			if errors.Is(err, moderation.ErrRejected) {
+				responseError(writer, http.StatusBadRequest, "Poll option contains a banned word", err)
+				return pollData{}, false
+			}
+			responseError(writer, http.StatusInternalServerError, "Error cleaning up poll option", err)
+			return pollData{}, false
+		}
+		prepared.Options[i] = cleaned
+	}
+
+	return prepared, true
+}
+
+
+func storePoll(ctx context.Context, queries *database.Queries, chirpID uuid.UUID, poll pollData) error {
+	_, err := queries.CreatePoll(ctx, database.CreatePollParams{
+		ChirpID: chirpID,
+		ClosesAt: poll.ClosesAt,
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, option := range poll.Options {
+		err := queries.CreatePollOption(ctx, database.CreatePollOptionParams{
+			ChirpID: chirpID,
+			Position: int32(i),
+			Text: option,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+
+// buildPoll assembles the API view of a poll for one viewer.
+func buildPoll(poll database.Poll, options []database.PollOption, votedOptionID *uuid.UUID, now time.Time) *Poll {
+	built := &Poll{
+		ClosesAt: poll.ClosesAt,
+		Closed: !now.Before(poll.ClosesAt),
+		Options: make([]PollOption, len(options)),
+		VotedOptionID: votedOptionID,
+	}
+
+	showResults := built.Closed || votedOptionID != nil
+	var total int32
+	for i, option := range options {
+		built.Options[i] = PollOption{ID: option.ID, Text: option.Text}
+		if showResults {
+			votes := option.VoteCount
+			built.Options[i].Votes = &votes
+			total += votes
+		}
+	}
+	if showResults {
+		built.TotalVotes = &total
+	}
+
+	return built
+}
+
+
+// withoutViewer returns a copy of the poll as an anonymous viewer would see
+// it, for payloads that are shared between users.
+func (poll Poll) withoutViewer() *Poll {
+	poll.VotedOptionID = nil
+	if poll.Closed {
+		return &poll
+	}
+
+	poll.TotalVotes = nil
+	options := make([]PollOption, len(poll.Options))
+	for i, option := range poll.Options {
+		option.Votes = nil
+		options[i] = option
+	}
+	poll.Options = options
+	return &poll
+}
+
+
+// decoratePolls attaches polls, and the viewer's votes on them, to the chirps
+// that have one.
+func (cfg *apiConfig) decoratePolls(ctx context.Context, viewerID uuid.UUID, chirps []Chirp, chirpIDs []uuid.UUID) error {
+	polls, err := cfg.dbQueries.ListPolls(ctx, chirpIDs)
+	if err != nil {
+		return err
+	}
+	if len(polls) == 0 {
+		return nil
+	}
+
+	pollChirpIDs := make([]uuid.UUID, len(polls))
+	for i, poll := range polls {
+		pollChirpIDs[i] = poll.ChirpID
+	}
+
+	options, err := cfg.dbQueries.ListPollOptions(ctx, pollChirpIDs)
+	if err != nil {
+		return err
+	}
+
+	chirpOptions := make(map[uuid.UUID][]database.PollOption)
+	for _, option := range options {
+		chirpOptions[option.ChirpID] = append(chirpOptions[option.ChirpID], option)
+	}
+
+	votedOptions := make(map[uuid.UUID]uuid.UUID)
+	if viewerID != uuid.Nil {
+		votes, err := cfg.dbQueries.ListPollVotes(ctx, database.ListPollVotesParams{
+			UserID: viewerID,
+			ChirpIds: pollChirpIDs,
+		})
+		if err != nil {
+			return err
+		}
+		for _, vote := range votes {
+			votedOptions[vote.ChirpID] = vote.OptionID
+		}
+	}
+
+	now := time.Now().UTC()
+	chirpPolls := make(map[uuid.UUID]*Poll, len(polls))
+	for _, poll := range polls {
+		var votedOptionID *uuid.UUID
+		if optionID, ok := votedOptions[poll.ChirpID]; ok {
+			votedOptionID = &optionID
+		}
+		chirpPolls[poll.ChirpID] = buildPoll(poll, chirpOptions[poll.ChirpID], votedOptionID, now)
+	}
+
+	for i := range chirps {
+		chirps[i].Poll = chirpPolls[chirps[i].ID]
+	}
+
+	return nil
+}
+
+
+// voteInPoll records the authenticated user's vote. Every user gets exactly
+// one vote per poll and it cannot be changed afterwards.
+func (cfg *apiConfig) voteInPoll(writer http.ResponseWriter, request *http.Request) {
+	type voteData struct {
+		OptionID uuid.UUID `json:"option_id"`
+	}
+
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := voteData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	chirp, err := cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+		ID: chirpID,
+		ViewerID: userID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+	if !chirp.Published {
+		responseError(writer, http.StatusBadRequest, "Chirp has not been published yet", nil)
+		return
+	}
+
+	poll, err := cfg.dbQueries.GetPoll(request.Context(), chirp.ID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not have a poll", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching poll", err)
+		return
+	}
+	if !time.Now().Before(poll.ClosesAt) {
+		responseError(writer, http.StatusConflict, "Poll has closed", nil)
+		return
+	}
+
+	err = cfg.dbQueries.CreatePollVote(request.Context(), database.CreatePollVoteParams{
+		ChirpID: chirp.ID,
+		UserID: userID,
+		OptionID: requestData.OptionID,
+	})
+	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok {
+			switch pqError.Code {
+			case "23505":
+				responseError(writer, http.StatusConflict, "You have already voted in this poll", err)
+				return
+			case "23503":
+				responseError(writer, http.StatusBadRequest, "Option does not belong to this poll", err)
+				return
+			}
+		}
+		responseError(writer, http.StatusInternalServerError, "Error saving vote", err)
+		return
+	}
+
+	chirps := []Chirp{chirpFromDB(chirp)}
+	if err := cfg.decorateChirps(request.Context(), userID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, chirps[0])
+}
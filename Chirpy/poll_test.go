@@ -0,0 +1,120 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+
+func TestValidatePoll(t *testing.T) {
+	opensAt := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		poll    pollData
+		wantErr bool
+	}{
+		{
+			name: "valid poll",
+			poll: pollData{Options: []string{"tabs", "spaces"}, ClosesAt: opensAt.Add(time.Hour)},
+		},
+		{
+			name:    "too few options",
+			poll:    pollData{Options: []string{"tabs"}, ClosesAt: opensAt.Add(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "too many options",
+			poll:    pollData{Options: []string{"a", "b", "c", "d", "e"}, ClosesAt: opensAt.Add(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "empty option",
+			poll:    pollData{Options: []string{"tabs", ""}, ClosesAt: opensAt.Add(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "option too long",
+			poll:    pollData{Options: []string{"tabs", strings.Repeat("a", maxPollOptionLength+1)}, ClosesAt: opensAt.Add(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "duplicate options",
+			poll:    pollData{Options: []string{"Tabs", "tabs"}, ClosesAt: opensAt.Add(time.Hour)},
+			wantErr: true,
+		},
+		{
+			name:    "closes too soon",
+			poll:    pollData{Options: []string{"tabs", "spaces"}, ClosesAt: opensAt.Add(time.Minute)},
+			wantErr: true,
+		},
+		{
+			name:    "stays open too long",
+			poll:    pollData{Options: []string{"tabs", "spaces"}, ClosesAt: opensAt.Add(30 * 24 * time.Hour)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePoll(tt.poll, opensAt)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("got error %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+
+func TestBuildPollHidesResults(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	chirpID := uuid.New()
+	options := []database.PollOption{
+		{ID: uuid.New(), ChirpID: chirpID, Position: 0, Text: "tabs", VoteCount: 3},
+		{ID: uuid.New(), ChirpID: chirpID, Position: 1, Text: "spaces", VoteCount: 2},
+	}
+	open := database.Poll{ChirpID: chirpID, ClosesAt: now.Add(time.Hour)}
+	closed := database.Poll{ChirpID: chirpID, ClosesAt: now.Add(-time.Hour)}
+
+	t.Run("open poll without a vote", func(t *testing.T) {
+		poll := buildPoll(open, options, nil, now)
+		if poll.Closed {
+			t.Errorf("poll should still be open")
+		}
+		if poll.TotalVotes != nil || poll.Options[0].Votes != nil {
+			t.Errorf("results should be hidden before voting")
+		}
+	})
+
+	t.Run("open poll after voting", func(t *testing.T) {
+		poll := buildPoll(open, options, &options[1].ID, now)
+		if poll.TotalVotes == nil || *poll.TotalVotes != 5 {
+			t.Fatalf("got total %v, want 5", poll.TotalVotes)
+		}
+		if *poll.Options[0].Votes != 3 || *poll.Options[1].Votes != 2 {
+			t.Errorf("got tallies %d/%d, want 3/2", *poll.Options[0].Votes, *poll.Options[1].Votes)
+		}
+
+		shared := poll.withoutViewer()
+		if shared.VotedOptionID != nil || shared.TotalVotes != nil || shared.Options[0].Votes != nil {
+			t.Errorf("shared copy should not carry the viewer's results")
+		}
+		if poll.Options[0].Votes == nil {
+			t.Errorf("withoutViewer modified the original poll")
+		}
+	})
+
+	t.Run("closed poll", func(t *testing.T) {
+		poll := buildPoll(closed, options, nil, now)
+		if !poll.Closed {
+			t.Errorf("poll should be closed")
+		}
+		if poll.TotalVotes == nil || *poll.TotalVotes != 5 {
+			t.Errorf("results should be visible once the poll has closed")
+		}
+	})
+}
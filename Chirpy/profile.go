@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/media"
+	"github.com/google/uuid"
+)
+
+const (
+	maxDisplayNameLength 	= 50
+	maxBioLength 			= 160
+	maxAvatarSize 			= 2 << 20
+)
+
+// handles are what bare @mentions match, so they share its character set
+var handlePattern = regexp.MustCompile(`^[A-Za-z0-9_]{3,30}$`)
+
+// PublicProfile is what anyone can see about a user. It never carries the
+// user's email.
+type PublicProfile struct {
+	ID 				uuid.UUID 	`json:"id"`
+	Handle 			string 		`json:"handle"`
+	DisplayName 	string 		`json:"display_name"`
+	Bio 			string 		`json:"bio"`
+	AvatarURL 		string 		`json:"avatar_url,omitempty"`
+	IsChirpyRed 	bool 		`json:"is_chirpy_red"`
+	CreatedAt 		time.Time 	`json:"created_at"`
+	FollowerCount 	int64 		`json:"follower_count"`
+	FollowingCount 	int64 		`json:"following_count"`
+}
+
+// Author is the compact profile embedded in every chirp.
+type Author struct {
+	ID 				uuid.UUID 	`json:"id"`
+	Handle 			string 		`json:"handle"`
+	DisplayName 	string 		`json:"display_name"`
+	AvatarURL 		string 		`json:"avatar_url,omitempty"`
+}
+
+
+func validateHandle(handle string) error {
+	if !handlePattern.MatchString(handle) {
+		return fmt.Errorf("Handles must be 3 to 30 letters, digits or underscores")
+	}
+	return nil
+}
+
+
+func validateProfileText(displayName, bio string) error {
+	if utf8.RuneCountInString(displayName) > maxDisplayNameLength {
+		return fmt.Errorf("Display name is too long")
+	}
+	if utf8.RuneCountInString(bio) > maxBioLength {
+		return fmt.Errorf("Bio is too long")
+	}
+	return nil
+}
+
+
+// generateHandle picks a placeholder handle for users who didn't ask for one.
+func generateHandle() (string, error) {
+	suffix := make([]byte, 6)
+	if _, err := rand.Read(suffix); err != nil {
+		return "", err
+	}
+	return "user_" + hex.EncodeToString(suffix), nil
+}
+
+
+func (cfg *apiConfig) avatarURL(avatarKey string) string {
+	if avatarKey == "" {
+		return ""
+	}
+	return cfg.storage.URL(avatarKey)
+}
+
+
+func (cfg *apiConfig) authorFromDB(user database.User) Author {
+	return Author{
+		ID: user.ID,
+		Handle: user.Handle,
+		DisplayName: user.DisplayName,
+		AvatarURL: cfg.avatarURL(user.AvatarKey),
+	}
+}
+
+
+// decorateAuthors embeds the author of each chirp, looking all of them up in
+// one query. Tombstones have no author.
+func (cfg *apiConfig) decorateAuthors(ctx context.Context, chirps []Chirp) error {
+	seen := map[uuid.UUID]bool{}
+	authorIDs := []uuid.UUID{}
+	for _, chirp := range chirps {
+		if chirp.UserID != uuid.Nil && !seen[chirp.UserID] {
+			seen[chirp.UserID] = true
+			authorIDs = append(authorIDs, chirp.UserID)
+		}
+	}
+	if len(authorIDs) == 0 {
+		return nil
+	}
+
+	users, err := cfg.dbQueries.GetUsersWithIDs(ctx, authorIDs)
+	if err != nil {
+		return err
+	}
+
+	authors := make(map[uuid.UUID]*Author, len(users))
+	for _, user := range users {
+		author := cfg.authorFromDB(user)
+		authors[user.ID] = &author
+	}
+
+	for i := range chirps {
+		chirps[i].Author = authors[chirps[i].UserID]
+	}
+
+	return nil
+}
+
+
+func (cfg *apiConfig) getProfile(writer http.ResponseWriter, request *http.Request) {
+	user, err := cfg.dbQueries.GetUserWithHandle(request.Context(), request.PathValue("handle"))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
+		return
+	}
+
+	counts, err := cfg.dbQueries.CountFollows(request.Context(), user.ID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error counting follows", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, PublicProfile{
+		ID: user.ID,
+		Handle: user.Handle,
+		DisplayName: user.DisplayName,
+		Bio: user.Bio,
+		AvatarURL: cfg.avatarURL(user.AvatarKey),
+		IsChirpyRed: user.IsChirpyRed,
+		CreatedAt: user.CreatedAt,
+		FollowerCount: counts.Followers,
+		FollowingCount: counts.Following,
+	})
+}
+
+
+// uploadAvatar replaces the caller's avatar with the image sent as the "file"
+// field of a multipart form. Only a thumbnail-sized copy is kept.
+func (cfg *apiConfig) uploadAvatar(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	request.Body = http.MaxBytesReader(writer, request.Body, maxAvatarSize+(1<<20))
+	if err := request.ParseMultipartForm(maxAvatarSize); err != nil {
+		var maxBytesError *http.MaxBytesError
+		if errors.As(err, &maxBytesError) {
+			responseError(writer, http.StatusRequestEntityTooLarge, "Avatar is too large", err)
+			return
+		}
+		responseError(writer, http.StatusBadRequest, "Malformed multipart form", err)
+		return
+	}
+	defer request.MultipartForm.RemoveAll()
+
+	file, header, err := request.FormFile("file")
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, "Missing file", err)
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxAvatarSize {
+		responseError(writer, http.StatusRequestEntityTooLarge, "Avatar is too large", nil)
+		return
+	}
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error reading upload", err)
+		return
+	}
+
+	img, err := media.DecodeImage(data)
+	if err != nil {
+		responseError(writer, http.StatusUnsupportedMediaType, err.Error(), err)
+		return
+	}
+
+	thumbnail, err := img.Thumbnail()
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error resizing avatar", err)
+		return
+	}
+
+	avatarKey := "avatar-" + uuid.New().String() + ".png"
+	if err := cfg.storage.Put(request.Context(), avatarKey, bytes.NewReader(thumbnail)); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error storing avatar", err)
+		return
+	}
+
+	cfg.replaceAvatar(writer, request, userID, avatarKey)
+}
+
+
+func (cfg *apiConfig) deleteAvatar(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	cfg.replaceAvatar(writer, request, userID, "")
+}
+
+
+// replaceAvatar points the user at a new avatar, or none, and removes the old
+// file once the change is saved. A newly stored file is removed if it isn't.
+func (cfg *apiConfig) replaceAvatar(writer http.ResponseWriter, request *http.Request, userID uuid.UUID, avatarKey string) {
+	discardNew := func() {
+		if avatarKey != "" {
+			cfg.deleteBlobs(request.Context(), avatarKey)
+		}
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		discardNew()
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	previous, err := queries.GetUserForUpdate(request.Context(), userID)
+	if err != nil {
+		discardNew()
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
+		return
+	}
+
+	updated, err := queries.SetUserAvatar(request.Context(), database.SetUserAvatarParams{
+		AvatarKey: avatarKey,
+		ID: userID,
+	})
+	if err != nil {
+		discardNew()
+		responseError(writer, http.StatusInternalServerError, "Error saving avatar", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		discardNew()
+		responseError(writer, http.StatusInternalServerError, "Error saving avatar", err)
+		return
+	}
+
+	if previous.AvatarKey != "" {
+		cfg.deleteBlobs(request.Context(), previous.AvatarKey)
+	}
+
+	responseJSON(writer, http.StatusOK, cfg.userFromDB(updated))
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// ChirpRevision is a body a chirp had before it was edited.
+type ChirpRevision struct {
+	ID 			uuid.UUID 	`json:"id"`
+	ChirpID 	uuid.UUID 	`json:"chirp_id"`
+	Body 		string 		`json:"body"`
+	CreatedAt 	time.Time 	`json:"created_at"`
+	ReplacedAt 	time.Time 	`json:"replaced_at"`
+}
+
+
+func (cfg *apiConfig) updateChirp(writer http.ResponseWriter, request *http.Request) {
+	type chirpData struct {
+		Body string `json:"body"`
+	}
+
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	entitlements, ok := cfg.requireEntitlements(writer, request, userID)
+	if !ok {
+		return
+	}
+
+	if !entitlements.CanEditChirps {
+		responseError(writer, http.StatusForbidden, "Editing chirps requires Chirpy Red", nil)
+		return
+	}
+
+	if !cfg.allowChirpWrite(writer, userID, entitlements) {
+		return
+	}
+
+	decoder := json.NewDecoder(request.Body)
+	requestData := chirpData{}
+	if err := decoder.Decode(&requestData); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
+		return
+	}
+
+	body, ok := cfg.prepareChirpBody(writer, requestData.Body, entitlements.MaxChirpLength)
+	if !ok {
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	// lock the row so concurrent edits can't lose a revision
+	current, err := queries.GetChirpForUpdate(request.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+
+	if current.UserID != userID {
+		responseError(writer, http.StatusForbidden, "Unauthorized request", nil)
+		return
+	}
+
+	if current.Body != body {
+		err = queries.CreateChirpRevision(request.Context(), database.CreateChirpRevisionParams{
+			ChirpID: current.ID,
+			Body: current.Body,
+			CreatedAt: current.UpdatedAt,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error saving chirp revision", err)
+			return
+		}
+
+		current, err = queries.UpdateChirpBody(request.Context(), database.UpdateChirpBodyParams{
+			ID: current.ID,
+			Body: body,
+		})
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error updating chirp", err)
+			return
+		}
+
+		// hashtags and mentions follow the new body
+		if err := queries.DeleteChirpTags(request.Context(), current.ID); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error updating hashtags", err)
+			return
+		}
+		if err := queries.DeleteChirpMentions(request.Context(), current.ID); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error updating mentions", err)
+			return
+		}
+		if err := storeChirpEntities(request.Context(), queries, current); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error saving hashtags and mentions", err)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error updating chirp", err)
+		return
+	}
+
+	chirps := []Chirp{chirpFromDB(current)}
+	if err := cfg.decorateChirps(request.Context(), userID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	if current.Published {
+		cfg.publishChirp(chirpUpdatedEvent, chirps[0])
+	}
+	responseJSON(writer, http.StatusOK, chirps[0])
+}
+
+
+// getChirpRevisions lists the earlier bodies of a chirp, newest first.
+func (cfg *apiConfig) getChirpRevisions(writer http.ResponseWriter, request *http.Request) {
+	viewerID, ok := cfg.optionalViewer(writer, request)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	_, err = cfg.dbQueries.GetChirp(request.Context(), database.GetChirpParams{
+		ID: chirpID,
+		ViewerID: viewerID,
+	})
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListChirpRevisions(request.Context(), chirpID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp revisions", err)
+		return
+	}
+
+	revisions := make([]ChirpRevision, len(rows))
+	for i, row := range rows {
+		revisions[i] = ChirpRevision(row)
+	}
+
+	responseJSON(writer, http.StatusOK, revisions)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// how often the publisher looks for scheduled chirps that are due
+const scheduledPublishInterval = 5 * time.Second
+
+
+// publishDueChirps makes scheduled chirps whose time has come visible and
+// announces them. The publishing UPDATE is atomic, so several Chirpy
+// instances can run it side by side without announcing a chirp twice.
+func (cfg *apiConfig) publishDueChirps(ctx context.Context) error {
+	published, err := cfg.dbQueries.PublishDueChirps(ctx)
+	if err != nil {
+		return err
+	}
+	if len(published) == 0 {
+		return nil
+	}
+
+	chirps := make([]Chirp, len(published))
+	for i, chirp := range published {
+		chirps[i] = chirpFromDB(chirp)
+	}
+
+	if err := cfg.decorateChirps(ctx, uuid.Nil, chirps); err != nil {
+		return err
+	}
+
+	for _, chirp := range chirps {
+		cfg.publishChirp(chirpCreatedEvent, chirp)
+	}
+
+	return nil
+}
+
+
+// getScheduledChirps lists the authenticated user's chirps that are still
+// waiting to be published, soonest first unless sort=desc is given.
+func (cfg *apiConfig) getScheduledChirps(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsRead)
+	if !ok {
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	// a scheduled chirp's created_at is its publish time, so the usual
+	// (created_at, id) cursors order the queue by when chirps go out
+	var dbChirps []database.Chirp
+	if page.scanDescending() {
+		dbChirps, err = cfg.dbQueries.ListScheduledChirpsDesc(request.Context(), database.ListScheduledChirpsDescParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		dbChirps, err = cfg.dbQueries.ListScheduledChirpsAsc(request.Context(), database.ListScheduledChirpsAscParams{
+			UserID: userID,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error fetching scheduled chirps: %s", err), err)
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, chirp := range dbChirps {
+		chirps[i] = chirpFromDB(chirp)
+	}
+
+	if err := cfg.decorateChirps(request.Context(), userID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, chirps, chirpPosition))
+}
+
+
+// cancelScheduledChirp removes a chirp that has not gone live yet. Nobody
+// else has seen it, so unlike a regular delete no tombstone is left behind.
+func (cfg *apiConfig) cancelScheduledChirp(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsWrite)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	// lock the row so the publisher can't flip it while we cancel
+	chirp, err := queries.GetChirpForUpdate(request.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+
+	if chirp.UserID != userID {
+		responseError(writer, http.StatusForbidden, "Unauthorized request", nil)
+		return
+	}
+
+	if chirp.Published {
+		responseError(writer, http.StatusConflict, "Chirp has already been published", nil)
+		return
+	}
+
+	removedAttachments, err := queries.DeleteChirpAttachments(request.Context(), chirp.ID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error deleting attachments", err)
+		return
+	}
+
+	// tags, mentions and revisions go with the row
+	if err := queries.DeleteScheduledChirp(request.Context(), chirp.ID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error cancelling chirp", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error cancelling chirp", err)
+		return
+	}
+
+	cfg.deleteAttachmentBlobs(request.Context(), removedAttachments)
+
+	writer.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+
+// authenticate finds the user behind the request's bearer token or personal
+// API key and checks that it was granted scope. It also returns everything
+// the credentials were granted. On failure it writes the error response
+// itself.
+func (cfg *apiConfig) authenticate(writer http.ResponseWriter, request *http.Request, scope string) (uuid.UUID, []string, bool) {
+	var userID uuid.UUID
+	var scopes []string
+
+	if strings.HasPrefix(request.Header.Get("Authorization"), "ApiKey ") {
+		key, err := auth.GetAPIKey(request.Header)
+		if err != nil {
+			responseError(writer, http.StatusUnauthorized, "Malformed/Missing API key", err)
+			return uuid.Nil, nil, false
+		}
+
+		apiKey, err := cfg.dbQueries.GetAPIKeyWithHash(request.Context(), auth.HashToken(key))
+		if err != nil {
+			if err == sql.ErrNoRows {
+				responseError(writer, http.StatusUnauthorized, "Invalid API key", err)
+				return uuid.Nil, nil, false
+			}
+			responseError(writer, http.StatusInternalServerError, "Error checking API key", err)
+			return uuid.Nil, nil, false
+		}
+
+		// only bookkeeping, so a failure doesn't fail the request
+		if err := cfg.dbQueries.TouchAPIKey(request.Context(), apiKey.ID); err != nil {
+			log.Printf("Error recording use of API key %s: %v", apiKey.ID, err)
+		}
+
+		userID, scopes = apiKey.UserID, apiKey.Scopes
+	} else {
+		accessToken, err := auth.GetBearerToken(request.Header)
+		if err != nil {
+			responseError(writer, http.StatusUnauthorized, "Missing/Malformed auth token in header", err)
+			return uuid.Nil, nil, false
+		}
+
+		userID, scopes, err = cfg.tokenKeys.ValidateJWT(accessToken)
+		if err != nil {
+			responseError(writer, http.StatusUnauthorized, "Invalid auth token", err)
+			return uuid.Nil, nil, false
+		}
+	}
+
+	if !auth.HasScope(scopes, scope) {
+		writer.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer error="insufficient_scope", scope="%s"`, scope))
+		responseError(writer, http.StatusForbidden, fmt.Sprintf("Token is missing the %s scope", scope), nil)
+		return uuid.Nil, nil, false
+	}
+
+	return userID, scopes, true
+}
+
+
+// optionalViewer returns the user behind the request's credentials, or
+// uuid.Nil when no Authorization header was sent at all. Credentials that are
+// present but invalid, or can't read chirps, still fail the request.
+func (cfg *apiConfig) optionalViewer(writer http.ResponseWriter, request *http.Request) (uuid.UUID, bool) {
+	if request.Header.Get("Authorization") == "" {
+		return uuid.Nil, true
+	}
+
+	viewerID, _, ok := cfg.authenticate(writer, request, auth.ScopeChirpsRead)
+	return viewerID, ok
+}
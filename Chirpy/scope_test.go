@@ -0,0 +1,59 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/google/uuid"
+)
+
+
+func TestAuthenticateScopes(t *testing.T) {
+	cfg := &apiConfig{tokenKeys: auth.NewKeySet("secret")}
+	userID := uuid.New()
+
+	readOnly, err := cfg.tokenKeys.MakeJWT(userID, []string{auth.ScopeChirpsRead}, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name 		string
+		header 		string
+		scope 		string
+		wantStatus 	int
+	}{
+		{name: "granted scope", header: "Bearer " + readOnly, scope: auth.ScopeChirpsRead, wantStatus: 0},
+		{name: "missing scope", header: "Bearer " + readOnly, scope: auth.ScopeChirpsWrite, wantStatus: http.StatusForbidden},
+		{name: "no header", header: "", scope: auth.ScopeChirpsRead, wantStatus: http.StatusUnauthorized},
+		{name: "bad token", header: "Bearer nope", scope: auth.ScopeChirpsRead, wantStatus: http.StatusUnauthorized},
+		{name: "malformed API key", header: "ApiKey ", scope: auth.ScopeChirpsRead, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			request := httptest.NewRequest("GET", "/api/chirps", nil)
+			if test.header != "" {
+				request.Header.Set("Authorization", test.header)
+			}
+			recorder := httptest.NewRecorder()
+
+			got, _, ok := cfg.authenticate(recorder, request, test.scope)
+			if test.wantStatus == 0 {
+				if !ok || got != userID {
+					t.Errorf("got (%v, %v), want (%v, true)", got, ok, userID)
+				}
+				return
+			}
+			if ok {
+				t.Fatalf("expected the request to be rejected")
+			}
+			if recorder.Code != test.wantStatus {
+				t.Errorf("got status %d, want %d", recorder.Code, test.wantStatus)
+			}
+		})
+	}
+}
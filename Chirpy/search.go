@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type searchResult struct {
+	Chirp
+	Rank 		float32 	`json:"rank"`
+	// Snippet is HTML: the matching part of the body, escaped, with hits
+	// wrapped in <mark> tags
+	Snippet 	string 		`json:"snippet"`
+}
+
+type searchResponse struct {
+	Items 		[]searchResult 	`json:"items"`
+	NextOffset 	*int32 			`json:"next_offset,omitempty"`
+}
+
+
+// searchChirps runs a full-text query over chirp bodies. Results are ordered
+// by relevance unless the client asks for sort=asc or sort=desc, in which case
+// they are ordered by creation time like the regular listing.
+func (cfg *apiConfig) searchChirps(writer http.ResponseWriter, request *http.Request) {
+	viewerID, ok := cfg.optionalViewer(writer, request)
+	if !ok {
+		return
+	}
+
+	query := request.URL.Query()
+
+	searchQuery := strings.TrimSpace(query.Get("q"))
+	if searchQuery == "" {
+		responseError(writer, http.StatusBadRequest, "Missing search query", nil)
+		return
+	}
+
+	// reuse the listing's limit and sort validation; search pages by offset
+	// because relevance ranks don't make stable cursors
+	if query.Has("after") || query.Has("before") {
+		responseError(writer, http.StatusBadRequest, "Search results are paginated with offset", nil)
+		return
+	}
+	page, err := parsePageRequest(query)
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	var offset int32
+	if offsetString := query.Get("offset"); offsetString != "" {
+		parsedOffset, err := strconv.ParseInt(offsetString, 10, 32)
+		if err != nil || parsedOffset < 0 {
+			responseError(writer, http.StatusBadRequest, "offset must be a non-negative integer", err)
+			return
+		}
+		offset = int32(parsedOffset)
+	}
+
+	var authorID uuid.NullUUID
+	if authorIDString := query.Get("author_id"); authorIDString != "" {
+		authorID.UUID, err = uuid.Parse(authorIDString)
+		if err != nil {
+			responseError(writer, http.StatusBadRequest, "Malformed User ID", err)
+			return
+		}
+		authorID.Valid = true
+	}
+
+	rows, err := cfg.dbQueries.SearchChirps(request.Context(), database.SearchChirpsParams{
+		Query: searchQuery,
+		AuthorID: authorID,
+		Sort: query.Get("sort"),
+		Limit: page.Limit + 1,
+		Offset: offset,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error searching chirps", err)
+		return
+	}
+
+	response := searchResponse{Items: []searchResult{}}
+	if len(rows) > int(page.Limit) {
+		rows = rows[:page.Limit]
+		nextOffset := offset + page.Limit
+		response.NextOffset = &nextOffset
+	}
+
+	chirps := make([]Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = chirpFromDB(row.Chirp)
+	}
+
+	if err := cfg.decorateChirps(request.Context(), viewerID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	for i, row := range rows {
+		response.Items = append(response.Items, searchResult{
+			Chirp: chirps[i],
+			Rank: row.Rank,
+			Snippet: row.Snippet,
+		})
+	}
+
+	responseJSON(writer, http.StatusOK, response)
+}
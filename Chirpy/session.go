@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// long user agents are cut down before they are stored
+const maxUserAgentLength = 512
+
+// Session is one login, i.e. a refresh token family, either a password login
+// or access granted to an OAuth client. Its ID stays the same while the tokens
+// in it are rotated.
+type Session struct {
+	ID 			uuid.UUID 	`json:"id"`
+	SignedInAt 	time.Time 	`json:"signed_in_at"`
+	LastUsedAt 	time.Time 	`json:"last_used_at"`
+	ExpiresAt 	time.Time 	`json:"expires_at"`
+	UserAgent 	string 		`json:"user_agent"`
+	IPAddress 	string 		`json:"ip_address"`
+	// the OAuth client the user signed in to, if any
+	ClientID 	*uuid.UUID 	`json:"client_id,omitempty"`
+}
+
+// clientInfo is what we record about the client a refresh token went to.
+type clientInfo struct {
+	userAgent 	string
+	ipAddress 	string
+}
+
+
+// clientInfoFrom describes the client behind a request. The address is the
+// connection's peer, so behind a proxy it is the proxy's.
+func clientInfoFrom(request *http.Request) clientInfo {
+	userAgent := request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	ipAddress, _, err := net.SplitHostPort(request.RemoteAddr)
+	if err != nil {
+		ipAddress = request.RemoteAddr
+	}
+
+	return clientInfo{userAgent: userAgent, ipAddress: ipAddress}
+}
+
+
+func (cfg *apiConfig) getSessions(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	rows, err := cfg.dbQueries.ListSessions(request.Context(), userID)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching sessions", err)
+		return
+	}
+
+	sessions := make([]Session, len(rows))
+	for i, row := range rows {
+		sessions[i] = Session{
+			ID: row.FamilyID,
+			SignedInAt: row.SignedInAt,
+			LastUsedAt: row.LastUsedAt,
+			ExpiresAt: row.ExpiresAt,
+			UserAgent: row.UserAgent,
+			IPAddress: row.IpAddress,
+		}
+		if row.ClientID.Valid {
+			sessions[i].ClientID = &row.ClientID.UUID
+		}
+	}
+
+	responseJSON(writer, http.StatusOK, sessions)
+}
+
+
+// deleteSession logs one of the caller's sessions out. Access tokens already
+// issued to it stay valid until they expire.
+func (cfg *apiConfig) deleteSession(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	sessionID, err := uuid.Parse(request.PathValue("sessionID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %v", err), err)
+		return
+	}
+
+	revoked, err := cfg.dbQueries.RevokeSession(request.Context(), database.RevokeSessionParams{
+		FamilyID: sessionID,
+		UserID: userID,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking session", err)
+		return
+	}
+	if revoked == 0 {
+		responseError(writer, http.StatusNotFound, "Session does not exist", nil)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
+
+
+// deleteAllSessions logs the caller out everywhere, including the session
+// making the request.
+func (cfg *apiConfig) deleteAllSessions(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	if err := cfg.dbQueries.RevokeUserRefreshTokens(request.Context(), userID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error revoking sessions", err)
+		return
+	}
+
+	writer.WriteHeader(http.StatusNoContent)
+}
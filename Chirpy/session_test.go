@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+
+func TestClientInfoFrom(t *testing.T) {
+	request := httptest.NewRequest("POST", "/api/login", nil)
+	request.RemoteAddr = "[2001:db8::1]:54321"
+	request.Header.Set("User-Agent", strings.Repeat("a", maxUserAgentLength+10))
+
+	client := clientInfoFrom(request)
+	if client.ipAddress != "2001:db8::1" {
+		t.Errorf("got address %q, want 2001:db8::1", client.ipAddress)
+	}
+	if len(client.userAgent) != maxUserAgentLength {
+		t.Errorf("got user agent of length %d, want %d", len(client.userAgent), maxUserAgentLength)
+	}
+}
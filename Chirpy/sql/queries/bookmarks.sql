@@ -0,0 +1,120 @@
+-- name: BookmarkChirp :exec
+INSERT INTO bookmarks (user_id, chirp_id, created_at)
+VALUES(
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (user_id, chirp_id) DO NOTHING;
+
+-- name: RemoveBookmark :exec
+DELETE
+FROM bookmarks
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: DeleteChirpBookmarks :exec
+DELETE
+FROM bookmarks
+WHERE chirp_id = $1;
+
+-- name: ListBookmarksAsc :many
+SELECT sqlc.embed(chirps), bookmarks.created_at AS saved_at
+FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (bookmarks.created_at, bookmarks.chirp_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY bookmarks.created_at, bookmarks.chirp_id
+LIMIT sqlc.arg('limit');
+
+-- name: ListBookmarksDesc :many
+SELECT sqlc.embed(chirps), bookmarks.created_at AS saved_at
+FROM bookmarks
+JOIN chirps ON chirps.id = bookmarks.chirp_id
+WHERE bookmarks.user_id = sqlc.arg('user_id')
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (bookmarks.created_at, bookmarks.chirp_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY bookmarks.created_at DESC, bookmarks.chirp_id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: CreateCollection :one
+INSERT INTO collections (id, user_id, name, created_at, updated_at)
+VALUES(
+    gen_random_uuid(),
+    $1,
+    $2,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetCollection :one
+SELECT *
+FROM collections
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteCollection :execrows
+DELETE
+FROM collections
+WHERE id = $1 AND user_id = $2;
+
+-- name: ListCollectionsAsc :many
+SELECT *
+FROM collections
+WHERE user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ListCollectionsDesc :many
+SELECT *
+FROM collections
+WHERE user_id = sqlc.arg('user_id')
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: AddChirpToCollection :exec
+INSERT INTO collection_chirps (collection_id, chirp_id, created_at)
+VALUES(
+    $1,
+    $2,
+    NOW()
+)
+ON CONFLICT (collection_id, chirp_id) DO NOTHING;
+
+-- name: RemoveChirpFromCollection :exec
+DELETE
+FROM collection_chirps
+WHERE collection_id = $1 AND chirp_id = $2;
+
+-- name: DeleteChirpFromCollections :exec
+DELETE
+FROM collection_chirps
+WHERE chirp_id = $1;
+
+-- name: ListCollectionChirpsAsc :many
+SELECT sqlc.embed(chirps), collection_chirps.created_at AS saved_at
+FROM collection_chirps
+JOIN chirps ON chirps.id = collection_chirps.chirp_id
+WHERE collection_chirps.collection_id = sqlc.arg('collection_id')
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (collection_chirps.created_at, collection_chirps.chirp_id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY collection_chirps.created_at, collection_chirps.chirp_id
+LIMIT sqlc.arg('limit');
+
+-- name: ListCollectionChirpsDesc :many
+SELECT sqlc.embed(chirps), collection_chirps.created_at AS saved_at
+FROM collection_chirps
+JOIN chirps ON chirps.id = collection_chirps.chirp_id
+WHERE collection_chirps.collection_id = sqlc.arg('collection_id')
+  AND chirps.deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (collection_chirps.created_at, collection_chirps.chirp_id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY collection_chirps.created_at DESC, collection_chirps.chirp_id DESC
+LIMIT sqlc.arg('limit');
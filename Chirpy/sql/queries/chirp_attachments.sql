@@ -0,0 +1,36 @@
+-- name: CreateChirpAttachment :one
+INSERT INTO chirp_attachments (id, chirp_id, created_at, content_type, size_bytes, width, height, storage_key, thumbnail_key)
+VALUES(
+    $1,
+    $2,
+    NOW(),
+    $3,
+    $4,
+    $5,
+    $6,
+    $7,
+    $8
+)
+RETURNING *;
+
+-- name: CountChirpAttachments :one
+SELECT COUNT(*)
+FROM chirp_attachments
+WHERE chirp_id = $1;
+
+-- name: ListChirpAttachments :many
+SELECT *
+FROM chirp_attachments
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY created_at, id;
+
+-- name: DeleteChirpAttachments :many
+DELETE
+FROM chirp_attachments
+WHERE chirp_id = $1
+RETURNING *;
+
+-- name: ListUserAttachments :many
+SELECT *
+FROM chirp_attachments
+WHERE chirp_id IN (SELECT id FROM chirps WHERE user_id = $1);
@@ -1,31 +1,183 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, parent_chirp_id, publish_at, published)
 VALUES(
     gen_random_uuid(),
+    COALESCE(sqlc.narg('publish_at')::timestamp, NOW()),
     NOW(),
-    NOW(),
-    $1,
-    $2
+    sqlc.arg('body'),
+    sqlc.arg('user_id'),
+    sqlc.narg('parent_chirp_id'),
+    sqlc.narg('publish_at')::timestamp,
+    sqlc.narg('publish_at')::timestamp IS NULL
 )
 RETURNING *;
 
--- name: AllChirps :many
+-- name: ListChirpsAsc :many
 SELECT *
 FROM chirps
-ORDER BY created_at;
+WHERE deleted_at IS NULL
+  AND (published OR user_id = sqlc.arg('viewer_id'))
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
 
--- name: GetChirpsByUser :many
+-- name: ListChirpsDesc :many
 SELECT *
 FROM chirps
-WHERE user_id = $1
-ORDER BY created_at;
+WHERE deleted_at IS NULL
+  AND (published OR user_id = sqlc.arg('viewer_id'))
+  AND (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id')::uuid)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
 
 -- name: GetChirp :one
 SELECT *
 FROM chirps
+WHERE id = sqlc.arg('id')
+  AND deleted_at IS NULL
+  AND (published OR user_id = sqlc.arg('viewer_id'));
+
+-- name: GetChirpForUpdate :one
+SELECT *
+FROM chirps
+WHERE id = $1 AND deleted_at IS NULL
+FOR UPDATE;
+
+-- name: GetChirpIncludingDeleted :one
+SELECT *
+FROM chirps
 WHERE id = $1;
 
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors AS (
+    SELECT parent.*, 1 AS depth
+    FROM chirps parent
+    JOIN chirps child ON child.parent_chirp_id = parent.id
+    WHERE child.id = sqlc.arg('id')
+    UNION ALL
+    SELECT parent.*, ancestors.depth + 1
+    FROM chirps parent
+    JOIN ancestors ON ancestors.parent_chirp_id = parent.id
+    WHERE ancestors.depth < sqlc.arg('max_depth')::int
+)
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, like_count, rechirp_count, search_vector, publish_at, published
+FROM ancestors
+ORDER BY depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants AS (
+    SELECT chirps.*, 1 AS depth
+    FROM chirps
+    WHERE parent_chirp_id = sqlc.arg('id')
+    UNION ALL
+    SELECT chirps.*, descendants.depth + 1
+    FROM chirps
+    JOIN descendants ON chirps.parent_chirp_id = descendants.id
+    WHERE descendants.depth < sqlc.arg('max_depth')::int
+)
+SELECT id, created_at, updated_at, body, user_id, parent_chirp_id, deleted_at, like_count, rechirp_count, search_vector, publish_at, published
+FROM descendants
+WHERE published
+ORDER BY created_at, id;
+
+-- name: UpdateChirpBody :one
+UPDATE chirps
+SET body = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
 -- name: DeleteChirp :exec
+UPDATE chirps
+SET body = '', deleted_at = NOW(), updated_at = NOW()
+WHERE id = $1;
+
+-- name: ListTimelineAsc :many
+SELECT *
+FROM chirps
+WHERE deleted_at IS NULL
+  AND published
+  AND (user_id = sqlc.arg('user_id')
+       OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id')))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ListTimelineDesc :many
+SELECT *
+FROM chirps
+WHERE deleted_at IS NULL
+  AND published
+  AND (user_id = sqlc.arg('user_id')
+       OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id')))
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: SearchChirps :many
+SELECT sqlc.embed(chirps),
+       ts_rank(chirps.search_vector, query)::real AS rank,
+       -- the body is escaped first so the <mark> tags are the only markup
+       ts_headline('english',
+                   replace(replace(replace(replace(replace(chirps.body,
+                       '&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;'), '''', '&#39;'),
+                   query,
+                   'StartSel=<mark>, StopSel=</mark>, MaxFragments=2')::text AS snippet
+FROM chirps, websearch_to_tsquery('english', sqlc.arg('query')) query
+WHERE chirps.deleted_at IS NULL
+  AND chirps.published
+  AND chirps.search_vector @@ query
+  AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id')::uuid)
+ORDER BY
+    CASE WHEN sqlc.arg('sort')::text = 'asc' THEN chirps.created_at END ASC,
+    CASE WHEN sqlc.arg('sort')::text = 'desc' THEN chirps.created_at END DESC,
+    rank DESC,
+    chirps.id
+LIMIT sqlc.arg('limit')
+OFFSET sqlc.arg('offset');
+
+-- name: ListScheduledChirpsAsc :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+  AND NOT published
+  AND deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ListScheduledChirpsDesc :many
+SELECT *
+FROM chirps
+WHERE user_id = sqlc.arg('user_id')
+  AND NOT published
+  AND deleted_at IS NULL
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (created_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: PublishDueChirps :many
+UPDATE chirps
+SET published = TRUE, updated_at = NOW()
+WHERE NOT published
+  AND deleted_at IS NULL
+  AND publish_at <= NOW()
+RETURNING *;
+
+-- name: DeleteScheduledChirp :exec
 DELETE
 FROM chirps
-WHERE id = $1;
+WHERE id = $1 AND NOT published;
+
+-- name: ListUserChirps :many
+SELECT *
+FROM chirps
+WHERE user_id = $1 AND deleted_at IS NULL
+ORDER BY created_at, id;
@@ -0,0 +1,67 @@
+-- name: CreateChirpTag :exec
+INSERT INTO chirp_tags (chirp_id, tag, created_at)
+VALUES(
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (chirp_id, tag) DO NOTHING;
+
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, mention_text)
+VALUES(
+    $1,
+    $2,
+    $3
+)
+ON CONFLICT (chirp_id, user_id) DO NOTHING;
+
+-- name: DeleteChirpTags :exec
+DELETE
+FROM chirp_tags
+WHERE chirp_id = $1;
+
+-- name: DeleteChirpMentions :exec
+DELETE
+FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: ListChirpMentions :many
+SELECT *
+FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListTagChirpsAsc :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.tag = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND chirps.published
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at, chirps.id
+LIMIT sqlc.arg('limit');
+
+-- name: ListTagChirpsDesc :many
+SELECT chirps.*
+FROM chirps
+JOIN chirp_tags ON chirp_tags.chirp_id = chirps.id
+WHERE chirp_tags.tag = sqlc.arg('tag')
+  AND chirps.deleted_at IS NULL
+  AND chirps.published
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('limit');
+
+-- name: TrendingTags :many
+SELECT chirp_tags.tag, COUNT(*) AS uses
+FROM chirp_tags
+JOIN chirps ON chirps.id = chirp_tags.chirp_id
+WHERE chirp_tags.created_at >= sqlc.arg('since')
+  AND chirps.deleted_at IS NULL
+  AND chirps.published
+GROUP BY chirp_tags.tag
+ORDER BY uses DESC, chirp_tags.tag
+LIMIT sqlc.arg('limit');
@@ -0,0 +1,48 @@
+-- name: RecordPolkaEvent :one
+INSERT INTO polka_events (id, webhook_id, event, payload, status, received_at)
+VALUES(
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3,
+    'pending',
+    NOW()
+)
+ON CONFLICT (webhook_id) DO NOTHING
+RETURNING *;
+
+-- name: GetPolkaEventByWebhookIDForUpdate :one
+SELECT *
+FROM polka_events
+WHERE webhook_id = $1
+FOR UPDATE;
+
+-- name: GetPolkaEventForUpdate :one
+SELECT *
+FROM polka_events
+WHERE id = $1
+FOR UPDATE;
+
+-- name: FinishPolkaEvent :one
+UPDATE polka_events
+SET status = $2, last_error = $3, attempts = attempts + 1, processed_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ListPolkaEventsAsc :many
+SELECT *
+FROM polka_events
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (received_at, id) > (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY received_at, id
+LIMIT sqlc.arg('limit');
+
+-- name: ListPolkaEventsDesc :many
+SELECT *
+FROM polka_events
+WHERE (sqlc.narg('status')::text IS NULL OR status = sqlc.narg('status')::text)
+  AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+       OR (received_at, id) < (sqlc.narg('cursor_created_at')::timestamp, sqlc.narg('cursor_id')::uuid))
+ORDER BY received_at DESC, id DESC
+LIMIT sqlc.arg('limit');
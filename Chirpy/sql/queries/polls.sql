@@ -0,0 +1,53 @@
+-- name: CreatePoll :one
+INSERT INTO polls (chirp_id, closes_at, created_at)
+VALUES(
+    $1,
+    $2,
+    NOW()
+)
+RETURNING *;
+
+-- name: CreatePollOption :exec
+INSERT INTO poll_options (id, chirp_id, position, text)
+VALUES(
+    gen_random_uuid(),
+    $1,
+    $2,
+    $3
+);
+
+-- name: GetPoll :one
+SELECT *
+FROM polls
+WHERE chirp_id = $1;
+
+-- name: ListPolls :many
+SELECT *
+FROM polls
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: ListPollOptions :many
+SELECT *
+FROM poll_options
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, position;
+
+-- name: ListPollVotes :many
+SELECT *
+FROM poll_votes
+WHERE user_id = sqlc.arg('user_id')
+  AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: CreatePollVote :exec
+INSERT INTO poll_votes (chirp_id, user_id, option_id, created_at)
+VALUES(
+    $1,
+    $2,
+    $3,
+    NOW()
+);
+
+-- name: DeletePoll :exec
+DELETE
+FROM polls
+WHERE chirp_id = $1;
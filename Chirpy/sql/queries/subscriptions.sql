@@ -0,0 +1,55 @@
+-- name: CreateSubscription :one
+INSERT INTO subscriptions (id, user_id, plan, status, started_at, current_period_end, created_at, updated_at)
+VALUES(
+    gen_random_uuid(),
+    $1,
+    $2,
+    'active',
+    NOW(),
+    $3,
+    NOW(),
+    NOW()
+)
+RETURNING *;
+
+-- name: GetLiveSubscriptionForUpdate :one
+SELECT *
+FROM subscriptions
+WHERE user_id = $1 AND expired_at IS NULL
+FOR UPDATE;
+
+-- name: GetLatestSubscription :one
+SELECT *
+FROM subscriptions
+WHERE user_id = $1
+ORDER BY started_at DESC
+LIMIT 1;
+
+-- name: RenewSubscription :one
+UPDATE subscriptions
+SET status = 'active', current_period_end = $2, renewed_at = NOW(), canceled_at = NULL, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: CancelSubscription :one
+UPDATE subscriptions
+SET status = 'canceled', canceled_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: EndSubscription :one
+UPDATE subscriptions
+SET status = 'expired', expired_at = NOW(), updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: ExpireLapsedSubscriptions :execrows
+WITH lapsed AS (
+    UPDATE subscriptions
+    SET status = 'expired', expired_at = NOW(), updated_at = NOW()
+    WHERE expired_at IS NULL AND current_period_end <= NOW()
+    RETURNING user_id
+)
+UPDATE users
+SET is_chirpy_red = false
+WHERE id IN (SELECT user_id FROM lapsed);
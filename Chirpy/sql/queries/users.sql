@@ -1,18 +1,28 @@
 -- name: CreateUser :one
-INSERT INTO users (id, created_at, updated_at, email, hashed_password)
+INSERT INTO users (id, created_at, updated_at, email, hashed_password, handle, display_name, bio)
 VALUES (
        gen_random_uuid(),
        NOW(),
        NOW(),
        $1,
-       $2
+       $2,
+       $3,
+       $4,
+       $5
 )
 RETURNING *;
 
--- name: UpdateUser :one
+-- name: PatchUser :one
 UPDATE users
-SET email = $1, hashed_password = $2
-WHERE id = $3
+SET email = COALESCE(sqlc.narg('email'), email),
+    hashed_password = COALESCE(sqlc.narg('hashed_password'), hashed_password),
+    handle = COALESCE(sqlc.narg('handle'), handle),
+    display_name = COALESCE(sqlc.narg('display_name'), display_name),
+    bio = COALESCE(sqlc.narg('bio'), bio),
+    -- a new address has to be verified again
+    email_verified_at = CASE WHEN email = COALESCE(sqlc.narg('email'), email) THEN email_verified_at END,
+    updated_at = NOW()
+WHERE id = sqlc.arg('id')
 RETURNING *;
 
 -- name: UpgradeUserToChirpyRed :one
@@ -21,6 +31,12 @@ SET is_chirpy_red = true
 WHERE id = $1
 RETURNING *;
 
+-- name: DowngradeUserFromChirpyRed :one
+UPDATE users
+SET is_chirpy_red = false
+WHERE id = $1
+RETURNING *;
+
 -- name: GetUserWithEmail :one
 SELECT *
 FROM users
@@ -31,5 +47,72 @@ SELECT *
 FROM users
 WHERE id = $1;
 
+-- name: GetUserForUpdate :one
+SELECT *
+FROM users
+WHERE id = $1
+FOR UPDATE;
+
+-- name: MarkEmailVerified :one
+UPDATE users
+SET email_verified_at = NOW(), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
+
+-- name: SetUserPassword :one
+UPDATE users
+SET hashed_password = $1, updated_at = NOW()
+WHERE id = $2 AND email = $3
+RETURNING *;
+
+-- name: ScheduleUserDeletion :one
+UPDATE users
+SET delete_after = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET delete_after = NULL, updated_at = NOW()
+WHERE id = $1 AND delete_after IS NOT NULL;
+
+-- name: ListUsersDueForDeletion :many
+SELECT id
+FROM users
+WHERE delete_after <= NOW()
+ORDER BY delete_after
+LIMIT $1;
+
+-- name: DeleteUser :exec
+DELETE
+FROM users
+WHERE id = $1;
+
 -- name: DeleteAllUsers :exec
 TRUNCATE TABLE users CASCADE;
+
+-- name: GetUsersWithEmails :many
+SELECT *
+FROM users
+WHERE email = ANY(sqlc.arg('emails')::text[]);
+
+-- name: GetUserWithHandle :one
+SELECT *
+FROM users
+WHERE lower(handle) = lower(sqlc.arg('handle'));
+
+-- name: GetUsersWithHandles :many
+SELECT *
+FROM users
+WHERE lower(handle) = ANY(sqlc.arg('handles')::text[]);
+
+-- name: GetUsersWithIDs :many
+SELECT *
+FROM users
+WHERE id = ANY(sqlc.arg('ids')::uuid[]);
+
+-- name: SetUserAvatar :one
+UPDATE users
+SET avatar_key = $1, updated_at = NOW()
+WHERE id = $2
+RETURNING *;
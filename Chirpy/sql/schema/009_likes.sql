@@ -0,0 +1,67 @@
+-- +goose Up
+CREATE TABLE chirp_likes(
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+       created_at TIMESTAMP NOT NULL,
+       PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE TABLE rechirps(
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+       created_at TIMESTAMP NOT NULL,
+       PRIMARY KEY (user_id, chirp_id)
+);
+
+ALTER TABLE chirps
+ADD COLUMN like_count INTEGER DEFAULT 0 NOT NULL,
+ADD COLUMN rechirp_count INTEGER DEFAULT 0 NOT NULL;
+
+-- counters are kept in step by triggers so that cascaded deletes
+-- (e.g. a user being removed) are accounted for as well
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_like_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET like_count = like_count + 1 WHERE id = NEW.chirp_id;
+    ELSE
+        UPDATE chirps SET like_count = like_count - 1 WHERE id = OLD.chirp_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose StatementBegin
+CREATE FUNCTION update_chirp_rechirp_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE chirps SET rechirp_count = rechirp_count + 1 WHERE id = NEW.chirp_id;
+    ELSE
+        UPDATE chirps SET rechirp_count = rechirp_count - 1 WHERE id = OLD.chirp_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER chirp_likes_count
+AFTER INSERT OR DELETE ON chirp_likes
+FOR EACH ROW EXECUTE FUNCTION update_chirp_like_count();
+
+CREATE TRIGGER rechirps_count
+AFTER INSERT OR DELETE ON rechirps
+FOR EACH ROW EXECUTE FUNCTION update_chirp_rechirp_count();
+
+-- +goose Down
+DROP TRIGGER rechirps_count ON rechirps;
+DROP TRIGGER chirp_likes_count ON chirp_likes;
+DROP FUNCTION update_chirp_rechirp_count();
+DROP FUNCTION update_chirp_like_count();
+
+ALTER TABLE chirps
+DROP COLUMN rechirp_count,
+DROP COLUMN like_count;
+
+DROP TABLE rechirps;
+DROP TABLE chirp_likes;
@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE banned_words(
+       word TEXT PRIMARY KEY,
+       created_at TIMESTAMP NOT NULL,
+       updated_at TIMESTAMP NOT NULL,
+       policy TEXT DEFAULT 'mask' NOT NULL,
+       replacement TEXT DEFAULT '' NOT NULL
+);
+
+INSERT INTO banned_words (word, created_at, updated_at)
+VALUES
+    ('kerfuffle', NOW(), NOW()),
+    ('sharbert', NOW(), NOW()),
+    ('fornax', NOW(), NOW());
+
+-- +goose Down
+DROP TABLE banned_words;
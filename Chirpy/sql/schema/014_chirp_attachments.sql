@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE chirp_attachments(
+       id UUID PRIMARY KEY,
+       chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+       created_at TIMESTAMP NOT NULL,
+       content_type TEXT NOT NULL,
+       size_bytes BIGINT NOT NULL,
+       width INTEGER NOT NULL,
+       height INTEGER NOT NULL,
+       storage_key TEXT NOT NULL,
+       thumbnail_key TEXT NOT NULL
+);
+
+CREATE INDEX chirp_attachments_chirp_id_idx ON chirp_attachments (chirp_id);
+
+-- +goose Down
+DROP TABLE chirp_attachments;
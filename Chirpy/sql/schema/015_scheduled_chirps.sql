@@ -0,0 +1,14 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN publish_at TIMESTAMP,
+ADD COLUMN published BOOLEAN NOT NULL DEFAULT TRUE;
+
+-- the publisher only ever looks at chirps that are still waiting
+CREATE INDEX chirps_scheduled_idx ON chirps (publish_at) WHERE NOT published;
+
+-- +goose Down
+DROP INDEX chirps_scheduled_idx;
+
+ALTER TABLE chirps
+DROP COLUMN published,
+DROP COLUMN publish_at;
@@ -0,0 +1,34 @@
+-- +goose Up
+CREATE TABLE bookmarks(
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+       created_at TIMESTAMP NOT NULL,
+       PRIMARY KEY (user_id, chirp_id)
+);
+
+CREATE INDEX bookmarks_user_id_created_at_idx ON bookmarks (user_id, created_at);
+CREATE INDEX bookmarks_chirp_id_idx ON bookmarks (chirp_id);
+
+CREATE TABLE collections(
+       id UUID PRIMARY KEY,
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       name TEXT NOT NULL,
+       created_at TIMESTAMP NOT NULL,
+       updated_at TIMESTAMP NOT NULL,
+       UNIQUE (user_id, name)
+);
+
+CREATE TABLE collection_chirps(
+       collection_id UUID NOT NULL REFERENCES collections(id) ON DELETE CASCADE,
+       chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+       created_at TIMESTAMP NOT NULL,
+       PRIMARY KEY (collection_id, chirp_id)
+);
+
+CREATE INDEX collection_chirps_collection_id_created_at_idx ON collection_chirps (collection_id, created_at);
+CREATE INDEX collection_chirps_chirp_id_idx ON collection_chirps (chirp_id);
+
+-- +goose Down
+DROP TABLE collection_chirps;
+DROP TABLE collections;
+DROP TABLE bookmarks;
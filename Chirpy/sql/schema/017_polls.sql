@@ -0,0 +1,53 @@
+-- +goose Up
+CREATE TABLE polls(
+       chirp_id UUID PRIMARY KEY REFERENCES chirps(id) ON DELETE CASCADE,
+       closes_at TIMESTAMP NOT NULL,
+       created_at TIMESTAMP NOT NULL
+);
+
+CREATE TABLE poll_options(
+       id UUID PRIMARY KEY,
+       chirp_id UUID NOT NULL REFERENCES polls(chirp_id) ON DELETE CASCADE,
+       position INTEGER NOT NULL,
+       text TEXT NOT NULL,
+       vote_count INTEGER DEFAULT 0 NOT NULL,
+       UNIQUE (chirp_id, position),
+       -- lets votes check that the option belongs to the poll being voted on
+       UNIQUE (chirp_id, id)
+);
+
+CREATE TABLE poll_votes(
+       chirp_id UUID NOT NULL REFERENCES polls(chirp_id) ON DELETE CASCADE,
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       option_id UUID NOT NULL,
+       created_at TIMESTAMP NOT NULL,
+       PRIMARY KEY (chirp_id, user_id),
+       FOREIGN KEY (chirp_id, option_id) REFERENCES poll_options(chirp_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX poll_votes_user_id_idx ON poll_votes (user_id);
+
+-- +goose StatementBegin
+CREATE FUNCTION update_poll_option_vote_count() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        UPDATE poll_options SET vote_count = vote_count + 1 WHERE id = NEW.option_id;
+    ELSE
+        UPDATE poll_options SET vote_count = vote_count - 1 WHERE id = OLD.option_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER poll_votes_count
+AFTER INSERT OR DELETE ON poll_votes
+FOR EACH ROW EXECUTE FUNCTION update_poll_option_vote_count();
+
+-- +goose Down
+DROP TRIGGER poll_votes_count ON poll_votes;
+DROP FUNCTION update_poll_option_vote_count();
+
+DROP TABLE poll_votes;
+DROP TABLE poll_options;
+DROP TABLE polls;
@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE polka_events(
+       id UUID PRIMARY KEY,
+       -- Polka's own id for the delivery; retries reuse it
+       webhook_id TEXT NOT NULL UNIQUE,
+       event TEXT NOT NULL,
+       payload JSONB NOT NULL,
+       status TEXT NOT NULL,
+       last_error TEXT NOT NULL DEFAULT '',
+       attempts INTEGER NOT NULL DEFAULT 0,
+       received_at TIMESTAMP NOT NULL,
+       processed_at TIMESTAMP
+);
+
+CREATE INDEX polka_events_received_at_idx ON polka_events (received_at, id);
+
+-- +goose Down
+DROP TABLE polka_events;
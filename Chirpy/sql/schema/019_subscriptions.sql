@@ -0,0 +1,27 @@
+-- +goose Up
+CREATE TABLE subscriptions(
+       id UUID PRIMARY KEY,
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       plan TEXT NOT NULL,
+       status TEXT NOT NULL,
+       started_at TIMESTAMP NOT NULL,
+       current_period_end TIMESTAMP NOT NULL,
+       renewed_at TIMESTAMP,
+       canceled_at TIMESTAMP,
+       expired_at TIMESTAMP,
+       created_at TIMESTAMP NOT NULL,
+       updated_at TIMESTAMP NOT NULL
+);
+
+-- a user has at most one subscription that hasn't ended yet
+CREATE UNIQUE INDEX subscriptions_live_user_id_idx ON subscriptions (user_id) WHERE expired_at IS NULL;
+CREATE INDEX subscriptions_current_period_end_idx ON subscriptions (current_period_end) WHERE expired_at IS NULL;
+
+-- existing Red users get a fresh period so the expiry job can manage them
+INSERT INTO subscriptions (id, user_id, plan, status, started_at, current_period_end, created_at, updated_at)
+SELECT gen_random_uuid(), id, 'chirpy_red', 'active', NOW(), NOW() + INTERVAL '30 days', NOW(), NOW()
+FROM users
+WHERE is_chirpy_red;
+
+-- +goose Down
+DROP TABLE subscriptions;
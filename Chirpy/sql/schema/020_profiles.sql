@@ -0,0 +1,23 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN handle TEXT,
+ADD COLUMN display_name TEXT NOT NULL DEFAULT '',
+ADD COLUMN bio TEXT NOT NULL DEFAULT '',
+ADD COLUMN avatar_key TEXT NOT NULL DEFAULT '';
+
+-- existing users get a placeholder handle they can change later
+UPDATE users
+SET handle = 'user_' || substr(replace(id::text, '-', ''), 1, 12);
+
+ALTER TABLE users ALTER COLUMN handle SET NOT NULL;
+
+-- handles are matched case-insensitively but shown as chosen
+CREATE UNIQUE INDEX users_handle_idx ON users (lower(handle));
+
+-- +goose Down
+DROP INDEX users_handle_idx;
+ALTER TABLE users
+DROP COLUMN avatar_key,
+DROP COLUMN bio,
+DROP COLUMN display_name,
+DROP COLUMN handle;
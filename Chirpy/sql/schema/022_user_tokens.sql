@@ -0,0 +1,24 @@
+-- +goose Up
+ALTER TABLE users
+ADD COLUMN email_verified_at TIMESTAMP;
+
+-- single-use tokens mailed to users; only their hashes are kept
+CREATE TABLE user_tokens(
+       id UUID PRIMARY KEY,
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       purpose TEXT NOT NULL,
+       token_hash TEXT NOT NULL UNIQUE,
+       -- the address the token was sent to
+       email TEXT NOT NULL,
+       created_at TIMESTAMP NOT NULL,
+       expires_at TIMESTAMP NOT NULL,
+       used_at TIMESTAMP
+);
+
+CREATE INDEX user_tokens_user_id_idx ON user_tokens (user_id, purpose);
+
+-- +goose Down
+DROP TABLE user_tokens;
+
+ALTER TABLE users
+DROP COLUMN email_verified_at;
@@ -0,0 +1,39 @@
+-- +goose Up
+ALTER TABLE refresh_tokens
+ADD COLUMN id UUID,
+ADD COLUMN family_id UUID,
+ADD COLUMN rotated_at TIMESTAMP;
+
+-- tokens already handed out keep working, we just stop storing them raw
+UPDATE refresh_tokens
+SET id = gen_random_uuid(),
+    token = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+
+-- every existing token starts a family of its own
+UPDATE refresh_tokens
+SET family_id = id;
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_pkey;
+ALTER TABLE refresh_tokens RENAME COLUMN token TO token_hash;
+ALTER TABLE refresh_tokens ALTER COLUMN id SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN family_id SET NOT NULL;
+ALTER TABLE refresh_tokens ADD PRIMARY KEY (id);
+
+CREATE UNIQUE INDEX refresh_tokens_token_hash_idx ON refresh_tokens (token_hash);
+CREATE INDEX refresh_tokens_family_id_idx ON refresh_tokens (family_id);
+
+-- +goose Down
+-- hashes can't be turned back into tokens, so everyone has to log in again
+DELETE FROM refresh_tokens;
+
+DROP INDEX refresh_tokens_family_id_idx;
+DROP INDEX refresh_tokens_token_hash_idx;
+
+ALTER TABLE refresh_tokens DROP CONSTRAINT refresh_tokens_pkey;
+ALTER TABLE refresh_tokens RENAME COLUMN token_hash TO token;
+ALTER TABLE refresh_tokens ADD PRIMARY KEY (token);
+
+ALTER TABLE refresh_tokens
+DROP COLUMN rotated_at,
+DROP COLUMN family_id,
+DROP COLUMN id;
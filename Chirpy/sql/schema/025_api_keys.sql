@@ -0,0 +1,19 @@
+-- +goose Up
+-- personal API keys; only their hashes are kept
+CREATE TABLE api_keys(
+       id UUID PRIMARY KEY,
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       name TEXT NOT NULL,
+       key_hash TEXT NOT NULL UNIQUE,
+       -- the start of the key, so users can tell their keys apart
+       prefix TEXT NOT NULL,
+       scopes TEXT[] NOT NULL,
+       created_at TIMESTAMP NOT NULL,
+       last_used_at TIMESTAMP,
+       revoked_at TIMESTAMP
+);
+
+CREATE INDEX api_keys_user_id_idx ON api_keys (user_id);
+
+-- +goose Down
+DROP TABLE api_keys;
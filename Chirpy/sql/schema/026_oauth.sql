@@ -0,0 +1,48 @@
+-- +goose Up
+-- third-party apps that can ask users for access
+CREATE TABLE oauth_clients(
+       id UUID PRIMARY KEY,
+       owner_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       name TEXT NOT NULL,
+       redirect_uris TEXT[] NOT NULL,
+       -- empty for public clients, which can't keep a secret
+       secret_hash TEXT NOT NULL,
+       created_at TIMESTAMP NOT NULL
+);
+
+CREATE INDEX oauth_clients_owner_id_idx ON oauth_clients (owner_id);
+
+-- single-use authorization codes; only their hashes are kept
+CREATE TABLE oauth_codes(
+       code_hash TEXT PRIMARY KEY,
+       client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+       user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       redirect_uri TEXT NOT NULL,
+       scopes TEXT[] NOT NULL,
+       code_challenge TEXT NOT NULL,
+       -- the refresh token family the code is exchanged into, so it can be
+       -- revoked if the code is replayed
+       family_id UUID NOT NULL,
+       created_at TIMESTAMP NOT NULL,
+       expires_at TIMESTAMP NOT NULL,
+       used_at TIMESTAMP
+);
+
+-- refresh tokens issued to clients carry the client and what it was granted;
+-- the ones from password logins get everything
+ALTER TABLE refresh_tokens
+ADD COLUMN client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE,
+ADD COLUMN scopes TEXT[];
+
+UPDATE refresh_tokens
+SET scopes = ARRAY['chirps:read', 'chirps:write', 'account'];
+
+ALTER TABLE refresh_tokens ALTER COLUMN scopes SET NOT NULL;
+
+-- +goose Down
+ALTER TABLE refresh_tokens
+DROP COLUMN scopes,
+DROP COLUMN client_id;
+
+DROP TABLE oauth_codes;
+DROP TABLE oauth_clients;
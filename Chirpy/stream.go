@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/stream"
+	"github.com/google/uuid"
+)
+
+const (
+	chirpCreatedEvent = "chirp.created"
+	chirpUpdatedEvent = "chirp.updated"
+	chirpDeletedEvent = "chirp.deleted"
+
+	streamHistorySize = 1000
+	streamBufferSize  = 64
+	heartbeatInterval = 15 * time.Second
+)
+
+
+// publishChirp pushes a created or updated chirp to stream subscribers.
+// Viewer-specific fields are dropped since every subscriber gets the same
+// payload.
+func (cfg *apiConfig) publishChirp(eventType string, chirp Chirp) {
+	chirp.LikedByMe = nil
+	if chirp.Poll != nil {
+		chirp.Poll = chirp.Poll.withoutViewer()
+	}
+	data, err := json.Marshal(chirp)
+	if err != nil {
+		log.Printf("Error marshalling %s event: %v", eventType, err)
+		return
+	}
+	cfg.hub.Publish(eventType, chirp.UserID, data)
+}
+
+
+func (cfg *apiConfig) publishChirpDeleted(chirpID, authorID uuid.UUID) {
+	data, err := json.Marshal(struct {
+		ID uuid.UUID `json:"id"`
+	}{ID: chirpID})
+	if err != nil {
+		log.Printf("Error marshalling %s event: %v", chirpDeletedEvent, err)
+		return
+	}
+	cfg.hub.Publish(chirpDeletedEvent, authorID, data)
+}
+
+
+// streamChirps keeps the connection open and pushes chirp events as
+// Server-Sent Events. Clients can narrow the stream with ?author_id= and
+// resume after a disconnect with the Last-Event-ID header, or the
+// last_event_id query parameter for clients that can't set headers.
+func (cfg *apiConfig) streamChirps(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		responseError(writer, http.StatusInternalServerError, "Streaming is not supported", nil)
+		return
+	}
+
+	var filter stream.Filter
+	if authorIDString := request.URL.Query().Get("author_id"); authorIDString != "" {
+		authorID, err := uuid.Parse(authorIDString)
+		if err != nil {
+			responseError(writer, http.StatusBadRequest, "Malformed User ID", err)
+			return
+		}
+		filter = func(event stream.Event) bool {
+			return event.AuthorID == authorID
+		}
+	}
+
+	lastEventIDString := request.Header.Get("Last-Event-ID")
+	if lastEventIDString == "" {
+		lastEventIDString = request.URL.Query().Get("last_event_id")
+	}
+	var lastEventID uint64
+	if lastEventIDString != "" {
+		var err error
+		lastEventID, err = strconv.ParseUint(lastEventIDString, 10, 64)
+		if err != nil {
+			responseError(writer, http.StatusBadRequest, "Malformed last event ID", err)
+			return
+		}
+	}
+
+	events, replay, cancel := cfg.hub.Subscribe(filter, lastEventID)
+	defer cancel()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	// stop reverse proxies from buffering the stream
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		writeStreamEvent(writer, event)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case event, open := <-events:
+			if !open {
+				// we fell too far behind; the client reconnects and resumes
+				return
+			}
+			writeStreamEvent(writer, event)
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(writer, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+
+func writeStreamEvent(writer http.ResponseWriter, event stream.Event) {
+	fmt.Fprintf(writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
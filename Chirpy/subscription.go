@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	chirpyRedPlan = "chirpy_red"
+	// used when Polka doesn't tell us when the paid period ends
+	defaultSubscriptionPeriod 	= 30 * 24 * time.Hour
+	subscriptionExpiryInterval 	= time.Minute
+)
+
+// Subscription is the user's latest Chirpy Red subscription. Status is one
+// of active, canceled, expired or none. Canceled subscriptions keep their
+// perks until the current period ends.
+type Subscription struct {
+	Plan 				string 		`json:"plan,omitempty"`
+	Status 				string 		`json:"status"`
+	StartedAt 			*time.Time 	`json:"started_at,omitempty"`
+	CurrentPeriodEnd 	*time.Time 	`json:"current_period_end,omitempty"`
+	RenewedAt 			*time.Time 	`json:"renewed_at,omitempty"`
+	CanceledAt 			*time.Time 	`json:"canceled_at,omitempty"`
+	ExpiredAt 			*time.Time 	`json:"expired_at,omitempty"`
+}
+
+
+func subscriptionFromDB(subscription database.Subscription) Subscription {
+	startedAt := subscription.StartedAt
+	currentPeriodEnd := subscription.CurrentPeriodEnd
+	return Subscription{
+		Plan: subscription.Plan,
+		Status: subscription.Status,
+		StartedAt: &startedAt,
+		CurrentPeriodEnd: &currentPeriodEnd,
+		RenewedAt: nullTimePointer(subscription.RenewedAt),
+		CanceledAt: nullTimePointer(subscription.CanceledAt),
+		ExpiredAt: nullTimePointer(subscription.ExpiredAt),
+	}
+}
+
+
+func nullTimePointer(value sql.NullTime) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	return &value.Time
+}
+
+
+// startSubscription turns Chirpy Red on, either opening a new subscription or
+// extending the one that is still running.
+func startSubscription(ctx context.Context, queries *database.Queries, userID uuid.UUID, periodEnd time.Time) error {
+	if _, err := queries.UpgradeUserToChirpyRed(ctx, userID); err != nil {
+		return err
+	}
+
+	live, err := queries.GetLiveSubscriptionForUpdate(ctx, userID)
+	if err == sql.ErrNoRows {
+		_, err = queries.CreateSubscription(ctx, database.CreateSubscriptionParams{
+			UserID: userID,
+			Plan: chirpyRedPlan,
+			CurrentPeriodEnd: periodEnd,
+		})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = queries.RenewSubscription(ctx, database.RenewSubscriptionParams{
+		ID: live.ID,
+		CurrentPeriodEnd: periodEnd,
+	})
+	return err
+}
+
+
+// cancelSubscription stops the running subscription from renewing. The user
+// stays on Red until the expiry job ends it.
+func cancelSubscription(ctx context.Context, queries *database.Queries, userID uuid.UUID) error {
+	if _, err := queries.GetUserWithID(ctx, userID); err != nil {
+		return err
+	}
+
+	live, err := queries.GetLiveSubscriptionForUpdate(ctx, userID)
+	if err == sql.ErrNoRows {
+		// nothing running, so nothing to cancel
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = queries.CancelSubscription(ctx, live.ID)
+	return err
+}
+
+
+// endSubscription takes Chirpy Red away immediately.
+func endSubscription(ctx context.Context, queries *database.Queries, userID uuid.UUID) error {
+	if _, err := queries.DowngradeUserFromChirpyRed(ctx, userID); err != nil {
+		return err
+	}
+
+	live, err := queries.GetLiveSubscriptionForUpdate(ctx, userID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	_, err = queries.EndSubscription(ctx, live.ID)
+	return err
+}
+
+
+// expireLapsedSubscriptions ends subscriptions whose paid period is over and
+// clears the users' Red flag in the same statement.
+func (cfg *apiConfig) expireLapsedSubscriptions(ctx context.Context) error {
+	expired, err := cfg.dbQueries.ExpireLapsedSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+	if expired > 0 {
+		log.Printf("Expired %d Chirpy Red subscriptions", expired)
+	}
+	return nil
+}
+
+
+func (cfg *apiConfig) getMySubscription(writer http.ResponseWriter, request *http.Request) {
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
+		return
+	}
+
+	subscription, err := cfg.dbQueries.GetLatestSubscription(request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseJSON(writer, http.StatusOK, Subscription{Status: "none"})
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching subscription", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, subscriptionFromDB(subscription))
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+)
+
+type TrendingTag struct {
+	Tag 	string 	`json:"tag"`
+	Uses 	int64 	`json:"uses"`
+}
+
+
+func (cfg *apiConfig) getTagChirps(writer http.ResponseWriter, request *http.Request) {
+	viewerID, ok := cfg.optionalViewer(writer, request)
+	if !ok {
+		return
+	}
+
+	tag := normalizeTag(request.PathValue("tag"))
+	if tag == "" {
+		responseError(writer, http.StatusBadRequest, "Missing tag", nil)
+		return
+	}
+
+	page, err := parsePageRequest(request.URL.Query())
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	cursorCreatedAt, cursorID := page.cursorParams()
+
+	var dbChirps []database.Chirp
+	if page.scanDescending() {
+		dbChirps, err = cfg.dbQueries.ListTagChirpsDesc(request.Context(), database.ListTagChirpsDescParams{
+			Tag: tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	} else {
+		dbChirps, err = cfg.dbQueries.ListTagChirpsAsc(request.Context(), database.ListTagChirpsAscParams{
+			Tag: tag,
+			CursorCreatedAt: cursorCreatedAt,
+			CursorID: cursorID,
+			Limit: page.Limit + 1,
+		})
+	}
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirps", err)
+		return
+	}
+
+	chirps := make([]Chirp, len(dbChirps))
+	for i, chirp := range dbChirps {
+		chirps[i] = chirpFromDB(chirp)
+	}
+
+	if err := cfg.decorateChirps(request.Context(), viewerID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	responseJSON(writer, http.StatusOK, buildPage(page, chirps, chirpPosition))
+}
+
+
+// getTrendingTags ranks hashtags by how often they were used within the
+// sliding window given as a Go duration, e.g. ?window=6h.
+func (cfg *apiConfig) getTrendingTags(writer http.ResponseWriter, request *http.Request) {
+	window := defaultTrendingWindow
+	if windowString := request.URL.Query().Get("window"); windowString != "" {
+		parsedWindow, err := time.ParseDuration(windowString)
+		if err != nil || parsedWindow <= 0 {
+			responseError(writer, http.StatusBadRequest, "window must be a positive duration such as 24h", err)
+			return
+		}
+		window = min(parsedWindow, maxTrendingWindow)
+	}
+
+	limit := defaultTrendingLimit
+	if limitString := request.URL.Query().Get("limit"); limitString != "" {
+		parsedLimit, err := strconv.Atoi(limitString)
+		if err != nil || parsedLimit <= 0 {
+			responseError(writer, http.StatusBadRequest, "limit must be a positive integer", err)
+			return
+		}
+		limit = min(parsedLimit, maxPageLimit)
+	}
+
+	rows, err := cfg.dbQueries.TrendingTags(request.Context(), database.TrendingTagsParams{
+		Since: time.Now().UTC().Add(-window),
+		Limit: int32(limit),
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching trending tags", err)
+		return
+	}
+
+	trending := make([]TrendingTag, len(rows))
+	for i, row := range rows {
+		trending[i] = TrendingTag{Tag: row.Tag, Uses: row.Uses}
+	}
+
+	responseJSON(writer, http.StatusOK, trending)
+}
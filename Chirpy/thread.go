@@ -0,0 +1,122 @@
+package main
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// maxThreadDepth bounds how far up and down a conversation we walk.
+const maxThreadDepth = 50
+
+type threadNode struct {
+	Chirp
+	Replies []*threadNode `json:"replies"`
+}
+
+type chirpThread struct {
+	Ancestors 	[]Chirp 		`json:"ancestors"`
+	Chirp 		Chirp 			`json:"chirp"`
+	Replies 	[]*threadNode 	`json:"replies"`
+}
+
+
+func (cfg *apiConfig) getChirpThread(writer http.ResponseWriter, request *http.Request) {
+	viewerID, ok := cfg.optionalViewer(writer, request)
+	if !ok {
+		return
+	}
+
+	chirpID, err := uuid.Parse(request.PathValue("chirpID"))
+	if err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Malformed UUID: %s", err), err)
+		return
+	}
+
+	// deleted chirps can still anchor a thread
+	chirpData, err := cfg.dbQueries.GetChirpIncludingDeleted(request.Context(), chirpID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "Chirp does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp", err)
+		return
+	}
+	if !chirpData.Published && chirpData.UserID != viewerID {
+		responseError(writer, http.StatusNotFound, "Chirp does not exist", nil)
+		return
+	}
+
+	ancestorRows, err := cfg.dbQueries.GetChirpAncestors(request.Context(), database.GetChirpAncestorsParams{
+		ID: chirpID,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching thread ancestors", err)
+		return
+	}
+
+	descendantRows, err := cfg.dbQueries.GetChirpDescendants(request.Context(), database.GetChirpDescendantsParams{
+		ID: chirpID,
+		MaxDepth: maxThreadDepth,
+	})
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching thread replies", err)
+		return
+	}
+
+	// decorate the whole conversation in one go: ancestors first, then the
+	// chirp itself, then every descendant
+	rows := append(append(ancestorRows, chirpData), descendantRows...)
+	chirps := make([]Chirp, len(rows))
+	for i, row := range rows {
+		chirps[i] = chirpFromDB(row)
+	}
+
+	if err := cfg.decorateChirps(request.Context(), viewerID, chirps); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error fetching chirp details", err)
+		return
+	}
+
+	numAncestors := len(ancestorRows)
+	responseJSON(writer, http.StatusOK, chirpThread{
+		Ancestors: chirps[:numAncestors],
+		Chirp: chirps[numAncestors],
+		Replies: buildReplyTree(chirpID, chirps[numAncestors+1:]),
+	})
+}
+
+
+// buildReplyTree nests a flat, time-ordered list of descendants under
+// their parents, returning the direct replies to rootID.
+func buildReplyTree(rootID uuid.UUID, descendants []Chirp) []*threadNode {
+	nodes := make(map[uuid.UUID]*threadNode, len(descendants))
+	for _, descendant := range descendants {
+		nodes[descendant.ID] = &threadNode{
+			Chirp: descendant,
+			Replies: []*threadNode{},
+		}
+	}
+
+	rootReplies := []*threadNode{}
+	for _, descendant := range descendants {
+		if descendant.ParentChirpID == nil {
+			continue
+		}
+		node := nodes[descendant.ID]
+		parentID := *descendant.ParentChirpID
+		if parentID == rootID {
+			rootReplies = append(rootReplies, node)
+			continue
+		}
+		if parent, ok := nodes[parentID]; ok {
+			parent.Replies = append(parent.Replies, node)
+		}
+	}
+
+	return rootReplies
+}
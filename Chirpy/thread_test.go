@@ -0,0 +1,38 @@
+package main
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+
+func TestBuildReplyTree(t *testing.T) {
+	rootID := uuid.New()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	reply := database.Chirp{ID: uuid.New(), CreatedAt: base, ParentChirpID: uuid.NullUUID{UUID: rootID, Valid: true}}
+	nested := database.Chirp{ID: uuid.New(), CreatedAt: base.Add(time.Minute), ParentChirpID: uuid.NullUUID{UUID: reply.ID, Valid: true}}
+	deleted := database.Chirp{
+		ID:            uuid.New(),
+		CreatedAt:     base.Add(2 * time.Minute),
+		Body:          "gone",
+		ParentChirpID: uuid.NullUUID{UUID: rootID, Valid: true},
+		DeletedAt:     sql.NullTime{Time: base, Valid: true},
+	}
+
+	tree := buildReplyTree(rootID, []Chirp{chirpFromDB(reply), chirpFromDB(nested), chirpFromDB(deleted)})
+
+	if len(tree) != 2 {
+		t.Fatalf("got %d direct replies, want 2", len(tree))
+	}
+	if tree[0].ID != reply.ID || len(tree[0].Replies) != 1 || tree[0].Replies[0].ID != nested.ID {
+		t.Errorf("nested reply was not attached to its parent")
+	}
+	if !tree[1].Deleted || tree[1].Body != "" {
+		t.Errorf("deleted reply should be a tombstone, got %+v", tree[1].Chirp)
+	}
+}
@@ -1,53 +1,185 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"net/http"
 	"time"
 	"fmt"
 	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/auth"
+	"github.com/TheYorouzoya/boot-dev-golang/Chirpy/internal/database"
+	"github.com/google/uuid"
 )
 
+const (
+	accessTokenLifetime 		= time.Hour
+	refreshTokenLifetime 		= 60 * 24 * time.Hour
+	refreshTokenPruneInterval 	= time.Hour
+)
 
-func (cfg *apiConfig) refreshAccessToken(writer http.ResponseWriter, request *http.Request) {
-	headerToken, err := auth.GetBearerToken(request.Header)
+
+// tokenGrant is what a refresh token family was issued for.
+type tokenGrant struct {
+	userID 		uuid.UUID
+	familyID 	uuid.UUID
+	// set when the tokens went to an OAuth client
+	clientID 	uuid.NullUUID
+	scopes 		[]string
+}
+
+// why a refresh token was turned down; the messages are sent to the client
+var (
+	errInvalidRefreshToken 	= errors.New("Invalid refresh token")
+	errRevokedRefreshToken 	= errors.New("Refresh token already revoked")
+	errReusedRefreshToken 	= errors.New("Refresh token reuse detected, please log in again")
+	errExpiredRefreshToken 	= errors.New("Refresh token is expired")
+)
+
+
+// issueRefreshToken stores the hash of a new refresh token in the grant's
+// family, noting the client it goes to, and returns the token itself.
+func issueRefreshToken(ctx context.Context, queries *database.Queries, grant tokenGrant, client clientInfo) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
 	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Malformed authorization header", err)
-		return
+		return "", err
 	}
 
-	fetchedToken, err := cfg.dbQueries.GetRefreshToken(request.Context(), headerToken)
+	_, err = queries.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		TokenHash: auth.HashToken(refreshToken),
+		FamilyID: grant.familyID,
+		UserID: grant.userID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenLifetime),
+		UserAgent: client.userAgent,
+		IpAddress: client.ipAddress,
+		ClientID: grant.clientID,
+		Scopes: grant.scopes,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return refreshToken, nil
+}
+
+
+// rotateRefreshToken trades a refresh token for a new one in the same family
+// and returns what the family was granted. Each refresh token works once:
+// presenting one that was already rotated means it leaked, so the whole
+// family is revoked and its owner has to sign in again. Tokens only work for
+// the client they were issued to, with password logins having none.
+func (cfg *apiConfig) rotateRefreshToken(ctx context.Context, refreshToken string, clientID uuid.NullUUID, client clientInfo) (tokenGrant, string, error) {
+	tx, err := cfg.db.BeginTx(ctx, nil)
+	if err != nil {
+		return tokenGrant{}, "", err
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	// the row lock makes concurrent refreshes with the same token take turns,
+	// so only the first one gets through
+	fetchedToken, err := queries.GetRefreshTokenForUpdate(ctx, auth.HashToken(refreshToken))
 	if err != nil {
 		if err == sql.ErrNoRows {
-			responseError(writer, http.StatusUnauthorized, "Invalid refresh token", err)
-			return
+			return tokenGrant{}, "", errInvalidRefreshToken
 		}
-		responseError(writer, http.StatusInternalServerError, "Error fetching token from DB", err)
-		return
+		return tokenGrant{}, "", err
+	}
+
+	if fetchedToken.ClientID != clientID {
+		return tokenGrant{}, "", fmt.Errorf("%w: issued to client %v", errInvalidRefreshToken, fetchedToken.ClientID.UUID)
 	}
 
 	if fetchedToken.RevokedAt.Valid {
-		err = fmt.Errorf("Given refresh token is revoked at %s", fetchedToken.RevokedAt.Time)
-		responseError(writer, http.StatusUnauthorized, "Refresh token already revoked", err)
-		return
+		return tokenGrant{}, "", fmt.Errorf("%w at %s", errRevokedRefreshToken, fetchedToken.RevokedAt.Time)
+	}
+
+	if fetchedToken.RotatedAt.Valid {
+		if _, err := queries.RevokeRefreshTokenFamily(ctx, fetchedToken.FamilyID); err != nil {
+			return tokenGrant{}, "", err
+		}
+		if err := tx.Commit(); err != nil {
+			return tokenGrant{}, "", err
+		}
+		return tokenGrant{}, "", fmt.Errorf("%w: token %s was already rotated at %s", errReusedRefreshToken, fetchedToken.ID, fetchedToken.RotatedAt.Time)
 	}
 
 	if fetchedToken.ExpiresAt.Before(time.Now()) {
-		responseError(writer, http.StatusUnauthorized, "Refresh token is expired", fmt.Errorf("expired refresh token"))
+		return tokenGrant{}, "", errExpiredRefreshToken
+	}
+
+	if err := queries.RotateRefreshToken(ctx, fetchedToken.ID); err != nil {
+		return tokenGrant{}, "", err
+	}
+
+	grant := tokenGrant{
+		userID: fetchedToken.UserID,
+		familyID: fetchedToken.FamilyID,
+		clientID: fetchedToken.ClientID,
+		scopes: fetchedToken.Scopes,
+	}
+	newToken, err := issueRefreshToken(ctx, queries, grant, client)
+	if err != nil {
+		return tokenGrant{}, "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return tokenGrant{}, "", err
+	}
+
+	return grant, newToken, nil
+}
+
+
+// refreshTokenRejection returns the rotateRefreshToken error to show the
+// client, or nil when err is a server failure.
+func refreshTokenRejection(err error) error {
+	for _, rejection := range []error{errInvalidRefreshToken, errRevokedRefreshToken, errReusedRefreshToken, errExpiredRefreshToken} {
+		if errors.Is(err, rejection) {
+			return rejection
+		}
+	}
+	return nil
+}
+
+
+// refreshAccessToken trades a password login's refresh token for a new
+// access token and a new refresh token in the same family.
+func (cfg *apiConfig) refreshAccessToken(writer http.ResponseWriter, request *http.Request) {
+	headerToken, err := auth.GetBearerToken(request.Header)
+	if err != nil {
+		responseError(writer, http.StatusUnauthorized, "Malformed authorization header", err)
 		return
 	}
 
+	grant, refreshToken, err := cfg.rotateRefreshToken(request.Context(), headerToken, uuid.NullUUID{}, clientInfoFrom(request))
+	if err != nil {
+		if rejection := refreshTokenRejection(err); rejection != nil {
+			responseError(writer, http.StatusUnauthorized, rejection.Error(), err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error rotating refresh token", err)
+		return
+	}
 
-	accessToken, err := auth.MakeJWT(fetchedToken.UserID, cfg.tokenSecret, time.Duration(time.Hour * 24 * 60))
+	accessToken, err := cfg.tokenKeys.MakeJWT(grant.userID, grant.scopes, accessTokenLifetime)
 	if err != nil {
 		responseError(writer, http.StatusInternalServerError, "Error generating new access token", err)
 		return
 	}
 
-	responseJSON(writer, http.StatusOK, struct{Token string `json:"token"`}{Token: accessToken})
+	responseJSON(writer, http.StatusOK, struct{
+		Token string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}{
+		Token: accessToken,
+		RefreshToken: refreshToken,
+	})
 }
 
 
+// revokeRefreshToken logs out the session the token belongs to, revoking
+// every token in its family.
 func (cfg *apiConfig) revokeRefreshToken(writer http.ResponseWriter, request *http.Request) {
 	headerToken, err := auth.GetBearerToken(request.Header)
 	if err != nil {
@@ -55,7 +187,16 @@ func (cfg *apiConfig) revokeRefreshToken(writer http.ResponseWriter, request *ht
 		return
 	}
 
-	if _, err = cfg.dbQueries.RevokeRefreshToken(request.Context(), headerToken); err != nil {
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
+	if err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
+		return
+	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
+
+	fetchedToken, err := queries.GetRefreshTokenForUpdate(request.Context(), auth.HashToken(headerToken))
+	if err != nil {
 		if err == sql.ErrNoRows {
 			responseError(writer, http.StatusNotFound, "refresh token not found", err)
 			return
@@ -64,5 +205,33 @@ func (cfg *apiConfig) revokeRefreshToken(writer http.ResponseWriter, request *ht
 		return
 	}
 
+	if _, err := queries.RevokeRefreshTokenFamily(request.Context(), fetchedToken.FamilyID); err != nil {
+		responseError(writer, http.StatusInternalServerError, "error revoking refresh token", err)
+		return
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "error revoking refresh token", err)
+		return
+	}
+
 	writer.WriteHeader(http.StatusNoContent)
 }
+
+
+// getJWKS publishes the public keys access tokens can be verified with, so
+// other services don't need the signing key or the shared secret.
+func (cfg *apiConfig) getJWKS(writer http.ResponseWriter, request *http.Request) {
+	// verifiers may cache the set for a while, so new keys should be added
+	// well before they are used for signing
+	writer.Header().Set("Cache-Control", "public, max-age=300")
+	responseJSON(writer, http.StatusOK, cfg.tokenKeys.JWKS())
+}
+
+
+// pruneRefreshTokens drops expired tokens. Rotated ones are kept until then
+// so reuse can still be detected.
+func (cfg *apiConfig) pruneRefreshTokens(ctx context.Context) error {
+	_, err := cfg.dbQueries.DeleteExpiredRefreshTokens(ctx)
+	return err
+}
@@ -18,13 +18,24 @@ type User struct {
 	CreatedAt 		time.Time 	`json:"created_at"`
 	UpdatedAt 		time.Time 	`json:"updated_at"`
 	Email 			string 		`json:"email"`
+	EmailVerified 	bool 		`json:"email_verified"`
 	HashedPassword 	string 		`json:"-"`
 	IsChirpyRed		bool 		`json:"is_chirpy_red"`
+	Handle 			string 		`json:"handle"`
+	DisplayName 	string 		`json:"display_name"`
+	Bio 			string 		`json:"bio"`
+	AvatarURL 		string 		`json:"avatar_url,omitempty"`
+	// set while the account is waiting to be deleted
+	DeleteAfter 	*time.Time 	`json:"delete_after,omitempty"`
 }
 
 type userData struct {
 	Email string `json:"email"`
 	Password string `json:"password"`
+	// profile fields, only read when signing up
+	Handle string `json:"handle"`
+	DisplayName string `json:"display_name"`
+	Bio string `json:"bio"`
 }
 
 type tokenResponse struct {
@@ -34,6 +45,23 @@ type tokenResponse struct {
 	}
 
 
+func (cfg *apiConfig) userFromDB(user database.User) User {
+	return User{
+		ID: user.ID,
+		CreatedAt: user.CreatedAt,
+		UpdatedAt: user.UpdatedAt,
+		Email: user.Email,
+		EmailVerified: user.EmailVerifiedAt.Valid,
+		HashedPassword: user.HashedPassword,
+		IsChirpyRed: user.IsChirpyRed,
+		Handle: user.Handle,
+		DisplayName: user.DisplayName,
+		Bio: user.Bio,
+		AvatarURL: cfg.avatarURL(user.AvatarKey),
+		DeleteAfter: nullTimePointer(user.DeleteAfter),
+	}
+}
+
 
 func (cfg *apiConfig) createUser(writer http.ResponseWriter, request *http.Request) {
 
@@ -45,23 +73,59 @@ func (cfg *apiConfig) createUser(writer http.ResponseWriter, request *http.Reque
 		return
 	}
 
+	if err := validateEmail(uData.Email); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	handle := uData.Handle
+	if handle == "" {
+		generated, err := generateHandle()
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error generating handle", err)
+			return
+		}
+		handle = generated
+	} else if err := validateHandle(handle); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	if err := validateProfileText(uData.DisplayName, uData.Bio); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
 	passHash, err := auth.HashPassword(uData.Password)
 	if err != nil {
 		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error hashing password: %s", err), err)
+		return
 	}
 
 	newUser, err := cfg.dbQueries.CreateUser(request.Context(), database.CreateUserParams{
 		Email: uData.Email,
 		HashedPassword: passHash,
+		Handle: handle,
+		DisplayName: uData.DisplayName,
+		Bio: uData.Bio,
 	})
 
 	if err != nil {
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			if pqError.Constraint == "users_handle_idx" {
+				responseError(writer, http.StatusConflict, "Handle already taken", err)
+				return
+			}
+			responseError(writer, http.StatusBadRequest, "Email already taken", err)
+			return
+		}
 		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error creating user: %s", err), err)
 		return
 	}
 
-	nUser := User(newUser)
-	responseJSON(writer, http.StatusCreated, nUser)
+	cfg.trySendVerificationEmail(request.Context(), newUser)
+
+	responseJSON(writer, http.StatusCreated, cfg.userFromDB(newUser))
 }
 
 
@@ -75,8 +139,6 @@ func (cfg *apiConfig) loginUser(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
-	defaultExpirationTime := time.Hour
-
 	usrData, err := cfg.dbQueries.GetUserWithEmail(request.Context(), uData.Email)
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -87,31 +149,34 @@ func (cfg *apiConfig) loginUser(writer http.ResponseWriter, request *http.Reques
 		return
 	}
 
-	user := User(usrData)
+	user := cfg.userFromDB(usrData)
 
 	if err = auth.CheckPasswordHash(uData.Password, user.HashedPassword); err != nil {
 		responseError(writer, http.StatusUnauthorized, "Incorrect email or password", err)
 		return
 	}
 
-	accessToken, err := auth.MakeJWT(user.ID, cfg.tokenSecret, defaultExpirationTime)
-	if err != nil {
-		responseError(writer, http.StatusInternalServerError, "Error creating JWT token", err)
-		return
+	// logging in during the grace period keeps the account
+	if user.DeleteAfter != nil {
+		if err := cfg.dbQueries.CancelUserDeletion(request.Context(), user.ID); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error restoring account", err)
+			return
+		}
+		user.DeleteAfter = nil
 	}
 
-	refreshToken, err := auth.MakeRefreshToken()
+	accessToken, err := cfg.tokenKeys.MakeJWT(user.ID, auth.AllScopes, accessTokenLifetime)
 	if err != nil {
-		responseError(writer, http.StatusInternalServerError, "Error creating refresh token", err)
+		responseError(writer, http.StatusInternalServerError, "Error creating JWT token", err)
 		return
 	}
 
-	_, err = cfg.dbQueries.CreateRefreshToken(request.Context(), database.CreateRefreshTokenParams{
-		Token: refreshToken,
-		UserID: user.ID,
-		ExpiresAt: time.Now().Add(time.Hour * 24 * 60),
-	})
-
+	// every login starts a new token family
+	refreshToken, err := issueRefreshToken(request.Context(), cfg.dbQueries, tokenGrant{
+		userID: user.ID,
+		familyID: uuid.New(),
+		scopes: auth.AllScopes,
+	}, clientInfoFrom(request))
 	if err != nil {
 		responseError(writer, http.StatusInternalServerError, "Error creating refresh token", err)
 		return
@@ -128,107 +193,156 @@ func (cfg *apiConfig) loginUser(writer http.ResponseWriter, request *http.Reques
 }
 
 
-func (cfg *apiConfig) updateUser(writer http.ResponseWriter, request *http.Request) {
+func nullString(value *string) sql.NullString {
+	if value == nil {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: *value, Valid: true}
+}
 
-	accessToken, err := auth.GetBearerToken(request.Header)
-	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Missing/Malformed auth token in header", err)
-		return
+
+// patchUser updates only the fields present in the request. Changing the
+// email or password needs the current password, and a new password signs
+// the user out everywhere by revoking their refresh tokens and API keys.
+func (cfg *apiConfig) patchUser(writer http.ResponseWriter, request *http.Request) {
+	type userPatch struct {
+		Email 			*string `json:"email"`
+		Password 		*string `json:"password"`
+		CurrentPassword string 	`json:"current_password"`
+		Handle 			*string `json:"handle"`
+		DisplayName 	*string `json:"display_name"`
+		Bio 			*string `json:"bio"`
 	}
 
-	userID, err := auth.ValidateJWT(accessToken, cfg.tokenSecret)
-	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Invalid auth token", err)
+	userID, _, ok := cfg.authenticate(writer, request, auth.ScopeAccount)
+	if !ok {
 		return
 	}
 
 	decoder := json.NewDecoder(request.Body)
-	uData := userData{}
-
-	if err := decoder.Decode(&uData); err != nil {
-		responseError(writer, http.StatusInternalServerError, fmt.Sprintf("Error decoding JSON: %s", err), err)
+	patch := userPatch{}
+	if err := decoder.Decode(&patch); err != nil {
+		responseError(writer, http.StatusBadRequest, fmt.Sprintf("Error decoding JSON: %s", err), err)
 		return
 	}
 
-	hashedPassword, err := auth.HashPassword(uData.Password)
-	if err != nil {
-		responseError(writer, http.StatusInternalServerError, "Error hashing password", err)
+	if patch.Email != nil {
+		if err := validateEmail(*patch.Email); err != nil {
+			responseError(writer, http.StatusBadRequest, err.Error(), err)
+			return
+		}
+	}
+	if patch.Password != nil && *patch.Password == "" {
+		responseError(writer, http.StatusBadRequest, "Password cannot be empty", nil)
 		return
 	}
-
-	updatedUser, err := cfg.dbQueries.UpdateUser(request.Context(), database.UpdateUserParams{
-		Email: uData.Email,
-		HashedPassword: hashedPassword,
-		ID: userID,
-	})
-	if err != nil {
-		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
-			responseError(writer, http.StatusBadRequest, "Email already taken", err)
+	if patch.Handle != nil {
+		if err := validateHandle(*patch.Handle); err != nil {
+			responseError(writer, http.StatusBadRequest, err.Error(), err)
 			return
 		}
-		responseError(writer, http.StatusInternalServerError, "Error updating user in DB", err)
-		return
 	}
-
-	finalResponse := struct{
-		Email string `json:"email"`
-		ID uuid.UUID `json:"id"`
-	}{
-		Email: updatedUser.Email,
-		ID: updatedUser.ID,
+	displayName, bio := "", ""
+	if patch.DisplayName != nil {
+		displayName = *patch.DisplayName
 	}
-
-	responseJSON(writer, http.StatusOK, finalResponse)
-}
-
-
-func (cfg *apiConfig) upgradeUserToChirpyRed(writer http.ResponseWriter, request *http.Request) {
-	type requestData struct {
-		Event string `json:"event"`
-		Data  struct {
-			UserID string `json:"user_id"`
-		} `json:"data"`
+	if patch.Bio != nil {
+		bio = *patch.Bio
+	}
+	if err := validateProfileText(displayName, bio); err != nil {
+		responseError(writer, http.StatusBadRequest, err.Error(), err)
+		return
 	}
 
-	apiKey, err := auth.GetAPIKey(request.Header)
+	tx, err := cfg.db.BeginTx(request.Context(), nil)
 	if err != nil {
-		responseError(writer, http.StatusUnauthorized, "Malformed/Missing API key", err)
+		responseError(writer, http.StatusInternalServerError, "Error starting transaction", err)
 		return
 	}
+	defer tx.Rollback()
+	queries := cfg.dbQueries.WithTx(tx)
 
-	if cfg.polkaKey != apiKey {
-		responseError(writer, http.StatusUnauthorized, "Invalid API key", err)
+	user, err := queries.GetUserForUpdate(request.Context(), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			responseError(writer, http.StatusNotFound, "User does not exist", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error fetching user", err)
 		return
 	}
 
-	var data requestData
+	// sending the email the account already has is not a change
+	if patch.Email != nil && *patch.Email == user.Email {
+		patch.Email = nil
+	}
 
-	decoder := json.NewDecoder(request.Body)
-	if err := decoder.Decode(&data); err != nil {
-		responseError(writer, http.StatusInternalServerError, "Error decoding JSON", err)
-		return
+	if patch.Email != nil || patch.Password != nil {
+		if patch.CurrentPassword == "" {
+			responseError(writer, http.StatusBadRequest, "Current password is required to change email or password", nil)
+			return
+		}
+		if err := auth.CheckPasswordHash(patch.CurrentPassword, user.HashedPassword); err != nil {
+			responseError(writer, http.StatusForbidden, "Incorrect current password", err)
+			return
+		}
 	}
 
-	if data.Event != "user.upgraded" {
-		writer.WriteHeader(http.StatusNoContent)
+	// the new address gets a verification mail, so it shares the mail limit
+	if patch.Email != nil && !cfg.allowMail(writer, *patch.Email) {
 		return
 	}
 
-	userID, err := uuid.Parse(data.Data.UserID)
+	var hashedPassword *string
+	if patch.Password != nil {
+		hashed, err := auth.HashPassword(*patch.Password)
+		if err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error hashing password", err)
+			return
+		}
+		hashedPassword = &hashed
+	}
+
+	updatedUser, err := queries.PatchUser(request.Context(), database.PatchUserParams{
+		Email: nullString(patch.Email),
+		HashedPassword: nullString(hashedPassword),
+		Handle: nullString(patch.Handle),
+		DisplayName: nullString(patch.DisplayName),
+		Bio: nullString(patch.Bio),
+		ID: userID,
+	})
 	if err != nil {
-		responseError(writer, http.StatusBadRequest, "Malformed UUID", err)
+		if pqError, ok := err.(*pq.Error); ok && pqError.Code == "23505" {
+			if pqError.Constraint == "users_handle_idx" {
+				responseError(writer, http.StatusConflict, "Handle already taken", err)
+				return
+			}
+			responseError(writer, http.StatusBadRequest, "Email already taken", err)
+			return
+		}
+		responseError(writer, http.StatusInternalServerError, "Error updating user in DB", err)
 		return
 	}
 
-	_, err = cfg.dbQueries.UpgradeUserToChirpyRed(request.Context(), userID)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			responseError(writer, http.StatusNotFound, "User does not exist", err)
+	if patch.Password != nil {
+		if err := queries.RevokeUserRefreshTokens(request.Context(), userID); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error revoking refresh tokens", err)
+			return
+		}
+		if err := queries.RevokeUserAPIKeys(request.Context(), userID); err != nil {
+			responseError(writer, http.StatusInternalServerError, "Error revoking API keys", err)
 			return
 		}
-		responseError(writer, http.StatusInternalServerError, "Error upgrading user", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		responseError(writer, http.StatusInternalServerError, "Error updating user in DB", err)
 		return
 	}
 
-	writer.WriteHeader(http.StatusNoContent)
+	if patch.Email != nil {
+		cfg.trySendVerificationEmail(request.Context(), updatedUser)
+	}
+
+	responseJSON(writer, http.StatusOK, cfg.userFromDB(updatedUser))
 }